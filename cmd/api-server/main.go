@@ -3,7 +3,10 @@ package main
 import (
 	"SearchService/config/server"
 	"SearchService/internal/handler/REST"
+	"SearchService/internal/imports"
+	"SearchService/internal/migrations"
 	"SearchService/internal/repository"
+	"SearchService/internal/util"
 	"context"
 	_ "github.com/lib/pq" // Импорт драйвера PostgreSQL
 	"log"
@@ -18,18 +21,54 @@ func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	if err := migrations.CheckVersion(server.DbConnectionString); err != nil {
+		log.Fatalf("схема БД не готова: %v (выполните cmd/migration/main_migrate_schema.go up)", err)
+	}
+
 	database := server.SetupDatabase()
 	defer database.Close()
 
 	httpServer, router := server.SetupRestServer()
 
 	esClient := server.SetupElasticSearch()
-	repo := repository.NewElasticRepository(esClient, "advertisements")
+	if err := util.EnsureAdvertisementsIndex(esClient); err != nil {
+		log.Fatalf("индекс объявлений не готов: %v", err)
+	}
+	repo := repository.NewElasticRepository(esClient, util.AdvertisementsIndex, server.SetupSearchFieldBoosts())
 
 	searchHandler := REST.NewSearchHandler(repo)
 	router.Get("/search", searchHandler.SearchInElastic)
 
+	suggestHandler := REST.NewSuggestHandler(repo)
+	router.Get("/suggest", suggestHandler.Suggest)
+
+	advertisementHandler := REST.NewAdvertisementHandler(
+		repository.NewAdvertisementRepository(database), util.NewElasticIndexer(esClient))
+	router.Post("/advertisements", advertisementHandler.Create)
+	router.Get("/advertisements/{id}", advertisementHandler.Get)
+	router.Put("/advertisements/{id}", advertisementHandler.Replace)
+	router.Patch("/advertisements/{id}", advertisementHandler.Patch)
+	router.Delete("/advertisements/{id}", advertisementHandler.Delete)
+
+	filling := util.NewDatabaseFilling(database)
+	filling.ColumnAliases = server.SetupCSVColumnAliases()
+	importRunner := imports.NewRunner(repository.NewImportJobRepository(database), filling.FillDatabaseFromCSV, imports.Options{})
+	importsDone := make(chan struct{})
+	go func() {
+		defer close(importsDone)
+		importRunner.Run(ctx)
+	}()
+
+	fillingHandler := REST.NewDatabaseFillingHandler(importRunner, server.SetupImportDirectory())
+	router.Post("/imports", fillingHandler.FillDatabaseAsync)
+	router.Get("/imports/{id}", fillingHandler.GetImport)
+	router.Post("/imports/{id}/cancel", fillingHandler.CancelImport)
+
 	runServer(ctx, httpServer)
+
+	// Текущее задание импорта завершается до закрытия подключения к БД
+	cancel()
+	<-importsDone
 }
 
 func runServer(ctx context.Context, server *http.Server) {
@@ -0,0 +1,67 @@
+package main
+
+// Этот файл возвращает события из топика недоставленных сообщений (KAFKA_DEAD_LETTER_TOPIC)
+// в исходный топик после того, как причина отказа устранена.
+//
+// Шаги, выполняемые в этом файле:
+//   1. Создаётся consumer отдельной группы KAFKA_GROUP_ID-dlq-replay с подпиской на DLQ.
+//   2. Каждое сообщение отправляется в топик из заголовка dlq.source.topic (или из флага -target)
+//      с исходными ключом, значением и заголовками; заголовки dlq.* убираются.
+//   3. Offset сообщения DLQ сохраняется только после подтверждённой доставки, поэтому при сбое
+//      повторный запуск продолжит с неотправленного сообщения.
+//   4. Команда завершается, когда новых сообщений нет дольше -idle-timeout или отправлено -limit сообщений.
+//
+// Пример запуска:
+//   go run main.go -limit=100 -idle-timeout=10s
+//
+// Сообщения, которые снова не удастся обработать, индексатор опять отправит в DLQ.
+
+import (
+	"SearchService/config/server"
+	"SearchService/internal/consumer"
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+func main() {
+	limit := flag.Int("limit", 0, "Максимальное количество возвращаемых сообщений (0 — все)")
+	idleTimeout := flag.Duration("idle-timeout", 10*time.Second, "Завершить работу, если новых сообщений нет дольше этого времени")
+	target := flag.String("target", "", "Топик для отправки вместо исходного из заголовка "+consumer.HeaderSourceTopic)
+	flag.Parse()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	err := run(ctx, *limit, *idleTimeout, *target)
+	stop()
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// run возвращает сообщения из dead-letter топика. Соединения закрываются до возврата,
+// чтобы offset'ы уже возвращённых сообщений были закоммичены и при ошибке.
+func run(ctx context.Context, limit int, idleTimeout time.Duration, target string) error {
+	options := server.SetupConsumerOptions()
+	deadLetterConsumer := server.SetupKafkaTopicConsumer(options.DeadLetterTopic, server.KafkaGroupID+"-dlq-replay")
+	defer func() {
+		if err := deadLetterConsumer.Close(); err != nil {
+			log.Printf("ошибка закрытия consumer'а: %v", err)
+		}
+	}()
+
+	producer := server.SetupKafkaProducer()
+	defer producer.Close()
+
+	replayer := consumer.NewDeadLetterReplayer(deadLetterConsumer, producer, target)
+	replayed, err := replayer.Run(ctx, limit, idleTimeout)
+	if err != nil {
+		return fmt.Errorf("возврат сообщений прерван после %d сообщений: %w", replayed, err)
+	}
+
+	log.Printf("Из %s возвращено %d сообщений", options.DeadLetterTopic, replayed)
+	return nil
+}
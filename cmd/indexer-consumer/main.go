@@ -0,0 +1,79 @@
+package main
+
+// Этот файл запускает инкрементальную индексацию объявлений из Kafka.
+//
+// Шаги, выполняемые в этом файле:
+//   1. Устанавливаются соединения с PostgreSQL и Elasticsearch, проверяется индекс advertisements.
+//   2. Создаётся consumer группы KAFKA_GROUP_ID с подпиской на KAFKA_ADVERTISEMENTS_TOPIC.
+//   3. Каждое событие (create, update, delete по id) применяется к индексу: для create и update
+//      документ берётся из события или загружается из БД, для delete — удаляется.
+//   4. Offset сообщения сохраняется только после успешной записи в Elasticsearch; при ошибке
+//      событие повторяется с растущей паузой, но не больше KAFKA_MAX_ATTEMPTS раз.
+//   5. Событие, которое не удалось разобрать или применить, публикуется без изменений в
+//      KAFKA_DEAD_LETTER_TOPIC с заголовками dlq.* (ошибка, число попыток, исходные топик,
+//      партиция и offset), после чего обработка партиции продолжается. Если DLQ недоступен
+//      дольше минуты, индексатор завершается с ненулевым кодом, не сохранив offset события.
+//      Вернуть такие события после исправления ошибки можно командой cmd/dlq-replay.
+//
+// Формат события задаётся KAFKA_EVENT_FORMAT:
+//   native   — {"type": "update", "id": 42, "advertisement": {...}}
+//   debezium — конверт Debezium по таблице advertisements (before/after/op, со схемой или без).
+//              Операции c, u и r (снимок) индексируют строку из after, d и tombstone-сообщения
+//              удаляют документ. Цена должна приходить числом или строкой
+//              (decimal.handling.mode=double или string в настройках коннектора).
+//
+// Пример запуска:
+//   go run main.go
+//
+// Работа завершается по SIGINT/SIGTERM; сохранённые offset'ы коммитятся при закрытии consumer'а.
+
+import (
+	"SearchService/config/server"
+	"SearchService/internal/consumer"
+	"SearchService/internal/repository"
+	"SearchService/internal/util"
+	"context"
+	"fmt"
+	"log"
+	"os/signal"
+	"syscall"
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	err := run(ctx)
+	stop()
+	if err != nil {
+		log.Fatalf("индексатор остановлен: %v", err)
+	}
+	log.Println("Индексатор остановлен")
+}
+
+// run работает до отмены ctx или ошибки обработки. Соединения закрываются до возврата,
+// чтобы при ошибке сохранённые offset'ы были закоммичены до завершения процесса с ненулевым кодом.
+func run(ctx context.Context) error {
+	database := server.SetupDatabase()
+	defer database.Close()
+
+	esClient := server.SetupElasticSearch()
+	if err := util.EnsureAdvertisementsIndex(esClient); err != nil {
+		return fmt.Errorf("индекс объявлений не готов: %w", err)
+	}
+
+	kafkaConsumer := server.SetupKafkaConsumer()
+	defer func() {
+		if err := kafkaConsumer.Close(); err != nil {
+			log.Printf("ошибка закрытия consumer'а: %v", err)
+		}
+	}()
+
+	options := server.SetupConsumerOptions()
+	producer := server.SetupKafkaProducer()
+	defer producer.Close()
+
+	repo := repository.NewAdvertisementRepository(database)
+	advertisementConsumer := consumer.NewAdvertisementConsumer(kafkaConsumer, producer, esClient, repo, options)
+
+	log.Println("Индексатор запущен, ожидание событий...")
+	return advertisementConsumer.Run(ctx)
+}
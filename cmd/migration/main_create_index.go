@@ -0,0 +1,35 @@
+package main
+
+// Этот файл создаёт индекс объявлений в Elasticsearch из управляемого маппинга
+// (internal/util/mappings/advertisements.json) или проверяет, что живой маппинг с ним совпадает.
+//
+// Пример запуска:
+//   go run main_create_index.go
+//
+// Если индекс уже существует и его маппинг расходится с ожидаемым, команда завершается с ошибкой
+// и выводит список расхождений. Изменить маппинг существующего индекса можно только переиндексацией.
+//
+// Используемые компоненты:
+//   - config.SetupElasticSearch() - инициализация подключения к Elasticsearch
+//   - util.EnsureAdvertisementsIndex — создание индекса или проверка маппинга
+
+import (
+	"SearchService/config/server"
+	"SearchService/internal/util"
+	"log"
+)
+
+func main() {
+	esClient := server.SetupElasticSearch()
+
+	version, err := util.AdvertisementsMappingVersion()
+	if err != nil {
+		log.Fatalf("ошибка чтения маппинга: %v", err)
+	}
+
+	if err := util.EnsureAdvertisementsIndex(esClient); err != nil {
+		log.Fatalf("ошибка подготовки индекса: %v", err)
+	}
+
+	log.Printf("Индекс %s готов, версия маппинга %d", util.AdvertisementsIndex, version)
+}
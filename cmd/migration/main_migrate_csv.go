@@ -2,7 +2,8 @@ package main
 
 // Этот файл запускает процесс миграции данных из CSV-файла в базу данных PostgreSQL.
 // Путь до CSV-файла передается через флаг командной строки -csv.
-// Данные обрабатываются пакетами (batch) для эффективной загрузки.
+// Данные обрабатываются пакетами (batch) для эффективной загрузки. Колонки сопоставляются
+// полям объявления по заголовку файла (см. util.CSVColumnAliases и CSV_COLUMN_ALIASES).
 //
 // Пример запуска:
 //   go run main_migrate_csv.go -csv=/path/to/ads.csv
@@ -30,10 +31,21 @@ func main() {
 	defer database.Close()
 
 	dbf := util.NewDatabaseFilling(database)
-	if err := dbf.FillDatabaseFromCSVAsync(*csvPath, 100); err != nil {
-		log.Fatalf("ошибка миграции: %v", err)
+	dbf.ColumnAliases = server.SetupCSVColumnAliases()
+	result, err := dbf.FillDatabaseFromCSVAsync(*csvPath, 100)
+	for _, batch := range result.FailedBatches {
+		log.Printf("не сохранены %v", batch)
+	}
+	if err != nil {
+		log.Fatalf("ошибка миграции (сохранено %d строк): %v", result.RowsSaved, err)
 	}
 
-	log.Println("Миграция завершена")
-	return
+	switch result.Status() {
+	case util.FillSucceeded:
+		log.Printf("Миграция завершена: сохранено %d строк", result.RowsSaved)
+	case util.FillPartial:
+		log.Fatalf("Миграция завершена частично: сохранено %d строк, не сохранено %d", result.RowsSaved, result.RowsFailed)
+	default:
+		log.Fatalf("Миграция не удалась: не сохранено %d строк", result.RowsFailed)
+	}
 }
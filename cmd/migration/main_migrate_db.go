@@ -1,18 +1,35 @@
 package main
 
 // Этот файл отвечает за инициализацию соединений с базой данных и Elasticsearch,
-// а также за выполнение полной миграции данных из PostgreSQL в Elasticsearch.
+// а также за выполнение полной переиндексации данных из PostgreSQL в Elasticsearch без простоя.
 //
 // Шаги, выполняемые в этом файле:
 //   1. Устанавливается соединение с PostgreSQL.
 //   2. Устанавливается соединение с Elasticsearch.
-//   3. Загружаются все объявления пакетами из БД.
-//   4. Индексируются объявления в Elasticsearch с использованием Bulk API.
+//   3. Создаётся новое поколение индекса advertisements_v{N} из управляемого маппинга.
+//   4. Объявления читаются из БД пакетами и параллельно индексируются в новое поколение
+//      несколькими bulk-воркерами; размер bulk-запроса ограничен числом документов и байтами.
+//   5. Отказы Bulk API по отдельным документам повторяются (429, 503) или попадают в отчёт,
+//      затем количество документов в новом поколении сверяется с количеством проиндексированных строк,
+//      а поля из флага -required-fields проверяются на пустые значения.
+//   6. Строки, изменённые в БД с начала выгрузки (по updated_at), догоняющим проходом переносятся
+//      в новое поколение — записи во время загрузки идут через алиас в старое.
+//   7. Алиас advertisements атомарно переключается на новое поколение, изменения, внесённые
+//      во время переключения, переносятся ещё одним проходом, старые поколения сверх значения
+//      флага -retention удаляются.
+//
+// Пока идёт загрузка, поиск продолжает работать со старым поколением. Если хотя бы один документ
+// не удалось проиндексировать, проверка не прошла или алиас не удалось переключить, новое поколение
+// удаляется, алиас не меняется, а отклонённые документы выводятся в лог с причиной отказа.
+//
+// Пример запуска:
+//   go run main_migrate_db.go -retention=1 -workers=8 -bulk-docs=2000 -bulk-bytes=10485760
+//   go run main_migrate_db.go -required-fields=product_name,brand,category
 //
 // Используемые компоненты:
 //   - config.SetupDatabase() — инициализация подключения к БД
 //	 - config.SetupElasticSearch() - инициализация подключения к Elasticsearch
-//   - indexer.MigrationAllAdvertisements — загрузка и вставка данных из БД в Elasticsearch
+//   - util.ReindexAdvertisements — построение нового поколения индекса и переключение алиаса
 //
 // Этот процесс можно использовать для первичной инициализации или переиндексации данных.
 
@@ -20,21 +37,67 @@ import (
 	"SearchService/config/server"
 	"SearchService/internal/repository"
 	"SearchService/internal/util"
+	"flag"
 	"log"
+	"strings"
 )
 
 func main() {
+	retention := flag.Int("retention", 1, "Сколько предыдущих поколений индекса сохранить после переключения алиаса")
+	workers := flag.Int("workers", util.DefaultPipelineOptions.Workers, "Количество параллельных bulk-воркеров")
+	fetchSize := flag.Int("fetch-size", util.DefaultPipelineOptions.FetchSize, "Количество строк, загружаемых из БД за один запрос")
+	bulkDocuments := flag.Int("bulk-docs", util.DefaultPipelineOptions.FlushDocuments, "Максимальное количество документов в bulk-запросе")
+	bulkBytes := flag.Int("bulk-bytes", util.DefaultPipelineOptions.FlushBytes, "Максимальный размер bulk-запроса в байтах")
+	requiredFields := flag.String("required-fields", strings.Join(util.DefaultRequiredFields, ","),
+		"Поля, которые не должны быть пустыми в проиндексированных документах (через запятую, пусто — без проверки)")
+	flag.Parse()
+
+	if *retention < 0 {
+		log.Fatal("Значение -retention не может быть отрицательным")
+	}
+
 	database := server.SetupDatabase()
 	defer database.Close()
 
 	esClient := server.SetupElasticSearch()
 	repo := repository.NewAdvertisementRepository(database)
 
-	err := util.MigrationAllAdvertisements(esClient, repo)
+	options := util.ReindexOptions{
+		Retention: *retention,
+		Pipeline: util.PipelineOptions{
+			Workers:        *workers,
+			FetchSize:      *fetchSize,
+			FlushDocuments: *bulkDocuments,
+			FlushBytes:     *bulkBytes,
+		},
+		RequiredFields: parseFieldList(*requiredFields),
+	}
+
+	result, err := util.ReindexAdvertisements(esClient, repo, options)
+	logFailures(result.Report)
 	if err != nil {
 		log.Fatalf("ошибка миграции: %v", err)
 	}
 
-	log.Println("Миграция завершена")
+	log.Printf("Миграция завершена: %d документов в %s, прежние индексы %v, удалены %v",
+		result.Report.Indexed, result.Index, result.Previous, result.Deleted)
 	return
 }
+
+// logFailures выводит документы, которые не удалось проиндексировать
+func logFailures(report util.IndexingReport) {
+	for _, failure := range report.Failures {
+		log.Printf("документ %s не проиндексирован: [%d] %s", failure.DocumentID, failure.Status, failure.Reason)
+	}
+}
+
+// parseFieldList разбирает список полей через запятую, пропуская пустые элементы
+func parseFieldList(value string) []string {
+	var fields []string
+	for _, field := range strings.Split(value, ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
@@ -0,0 +1,104 @@
+package main
+
+// Этот файл управляет схемой БД через встроенные миграции (internal/migrations).
+//
+// Команды:
+//   up          — применить все неприменённые миграции
+//   down [N]    — откатить N последних миграций (по умолчанию одну)
+//   goto V      — перейти к версии V вверх или вниз
+//   version     — вывести текущую и последнюю доступную версию
+//   force V     — отметить версию V применённой без выполнения миграций
+//                 (только для исправления «грязного» состояния после прерванной миграции)
+//
+// Пример запуска:
+//   go run main_migrate_schema.go up
+//   go run main_migrate_schema.go down 1
+//   go run main_migrate_schema.go goto 1
+//
+// API-сервер не запускается, если схема БД отстаёт от последней миграции.
+
+import (
+	"SearchService/config/server"
+	"SearchService/internal/migrations"
+	"errors"
+	"flag"
+	"github.com/golang-migrate/migrate/v4"
+	"log"
+	"strconv"
+)
+
+func main() {
+	flag.Parse()
+	if flag.NArg() == 0 {
+		log.Fatal("укажите команду: up, down [N], goto V, version или force V")
+	}
+
+	migrator, err := migrations.New(server.DbConnectionString)
+	if err != nil {
+		log.Fatalf("ошибка инициализации миграций: %v", err)
+	}
+	defer migrator.Close()
+
+	command, args := flag.Arg(0), flag.Args()[1:]
+	switch command {
+	case "up":
+		err = migrator.Up()
+	case "down":
+		steps := 1
+		if len(args) > 0 {
+			steps = parseNumber(args[0])
+		}
+		err = migrator.Steps(-steps)
+	case "goto":
+		if len(args) == 0 {
+			log.Fatal("goto: укажите версию")
+		}
+		err = migrator.Migrate(uint(parseNumber(args[0])))
+	case "force":
+		if len(args) == 0 {
+			log.Fatal("force: укажите версию")
+		}
+		err = migrator.Force(parseNumber(args[0]))
+	case "version":
+	default:
+		log.Fatalf("неизвестная команда: %s", command)
+	}
+
+	if errors.Is(err, migrate.ErrNoChange) {
+		log.Println("Схема БД уже в нужной версии")
+	} else if err != nil {
+		log.Fatalf("ошибка выполнения %s: %v", command, err)
+	}
+
+	logVersion(migrator)
+}
+
+func parseNumber(value string) int {
+	number, err := strconv.Atoi(value)
+	if err != nil || number <= 0 {
+		log.Fatalf("ожидается положительное число, получено %q", value)
+	}
+	return number
+}
+
+func logVersion(migrator *migrate.Migrate) {
+	latest, err := migrations.LatestVersion()
+	if err != nil {
+		log.Fatalf("ошибка чтения миграций: %v", err)
+	}
+
+	version, dirty, err := migrator.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		log.Printf("Миграции не применялись, последняя доступная версия %d", latest)
+		return
+	}
+	if err != nil {
+		log.Fatalf("ошибка получения версии схемы БД: %v", err)
+	}
+
+	if dirty {
+		log.Printf("Версия схемы БД %d (грязная — миграция была прервана), последняя доступная %d", version, latest)
+		return
+	}
+	log.Printf("Версия схемы БД %d, последняя доступная %d", version, latest)
+}
@@ -0,0 +1,82 @@
+package main
+
+// Этот файл выполняет инкрементальную синхронизацию объявлений из PostgreSQL в Elasticsearch —
+// промежуточный вариант между полной переиндексацией (main_migrate_db.go) и индексатором из Kafka.
+//
+// Шаги, выполняемые в этом файле:
+//   1. Устанавливаются соединения с PostgreSQL и Elasticsearch, проверяется индекс advertisements.
+//   2. Из таблицы sync_watermarks читается отметка (updated_at, id) последней синхронизации;
+//      если её нет, синхронизируются все строки.
+//   3. Строки, изменённые после отметки (но не позже now() - -lag), читаются пакетами в порядке
+//      (updated_at, id) и индексируются через Bulk API; мягко удалённые строки (deleted_at)
+//      удаляются из индекса.
+//   4. Отметка сдвигается после каждого успешного пакета. При отказе по документам работа
+//      останавливается, не сдвигая отметку, и следующий запуск повторит неудачный пакет.
+//   5. С флагом -once команда завершается после одного прохода, иначе повторяет его каждые -interval.
+//
+// Прерывание (SIGINT/SIGTERM) безопасно: прогресс до последнего подтверждённого пакета сохранён.
+//
+// Пример запуска:
+//   go run main_sync_db.go -once
+//   go run main_sync_db.go -interval=1m -fetch-size=2000
+
+import (
+	"SearchService/config/server"
+	"SearchService/internal/repository"
+	"SearchService/internal/util"
+	"context"
+	"flag"
+	"log"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+func main() {
+	once := flag.Bool("once", false, "Выполнить один проход синхронизации и завершиться")
+	interval := flag.Duration("interval", time.Minute, "Пауза между проходами синхронизации")
+	fetchSize := flag.Int("fetch-size", util.DefaultSyncOptions.FetchSize, "Количество строк в одном пакете")
+	lag := flag.Duration("lag", util.DefaultSyncOptions.Lag, "Не синхронизировать строки, изменённые позже этого времени назад")
+	flag.Parse()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	database := server.SetupDatabase()
+	defer database.Close()
+
+	esClient := server.SetupElasticSearch()
+	if err := util.EnsureAdvertisementsIndex(esClient); err != nil {
+		log.Fatalf("индекс объявлений не готов: %v", err)
+	}
+
+	repo := repository.NewAdvertisementRepository(database)
+	watermarks := repository.NewWatermarkRepository(database)
+	options := util.SyncOptions{FetchSize: *fetchSize, Lag: *lag}
+
+	for {
+		report, err := util.SyncChangedAdvertisements(ctx, esClient, repo, watermarks, options)
+		for _, failure := range report.Failures {
+			log.Printf("документ %s не проиндексирован: [%d] %s", failure.DocumentID, failure.Status, failure.Reason)
+		}
+		if err != nil {
+			if *once {
+				log.Fatalf("ошибка синхронизации: %v", err)
+			}
+			log.Printf("ошибка синхронизации: %v", err)
+		} else {
+			log.Printf("Проход синхронизации завершён: проиндексировано %d, удалено %d объявлений", report.Indexed, report.Deleted)
+		}
+
+		if *once {
+			return
+		}
+
+		select {
+		case <-time.After(*interval):
+		case <-ctx.Done():
+			log.Println("Синхронизация остановлена")
+			return
+		}
+	}
+}
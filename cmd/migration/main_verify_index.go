@@ -0,0 +1,67 @@
+package main
+
+// Этот файл сверяет индекс advertisements в Elasticsearch с таблицей advertisements в PostgreSQL.
+//
+// Шаги, выполняемые в этом файле:
+//   1. Устанавливаются соединения с PostgreSQL и Elasticsearch.
+//   2. Строки таблицы и документы индекса (по снимку point in time) читаются потоком в порядке id.
+//   3. Для каждой пары сравниваются контрольные суммы индексируемых полей; выводятся количество
+//      и примеры id отсутствующих (missing), лишних (extra) и устаревших (stale) документов.
+//   4. С флагом -repair отсутствующие и устаревшие документы индексируются заново из БД,
+//      а лишние удаляются из индекса.
+//
+// Команда завершается с кодом 1, если найдены расхождения (и не было -repair) или исправление не удалось.
+//
+// Пример запуска:
+//   go run main_verify_index.go
+//   go run main_verify_index.go --repair --fetch-size=2000
+
+import (
+	"SearchService/config/server"
+	"SearchService/internal/repository"
+	"SearchService/internal/util"
+	"flag"
+	"log"
+	"os"
+)
+
+func main() {
+	repair := flag.Bool("repair", false, "Исправить найденные расхождения")
+	fetchSize := flag.Int("fetch-size", util.DefaultVerifyOptions.FetchSize, "Количество строк и документов, читаемых за один запрос")
+	samples := flag.Int("samples", util.DefaultVerifyOptions.Samples, "Сколько id каждого вида расхождений вывести")
+	flag.Parse()
+
+	database := server.SetupDatabase()
+	defer database.Close()
+
+	esClient := server.SetupElasticSearch()
+	repo := repository.NewAdvertisementRepository(database)
+
+	options := util.VerifyOptions{FetchSize: *fetchSize, Samples: *samples, Repair: *repair}
+	report, err := util.VerifyAdvertisementsIndex(esClient, repo, options)
+	if err != nil {
+		log.Fatalf("ошибка сверки: %v", err)
+	}
+
+	log.Printf("Просмотрено строк БД: %d, документов индекса: %d", report.Rows, report.Documents)
+	for _, kind := range []util.DiffKind{util.DiffMissing, util.DiffExtra, util.DiffStale} {
+		log.Printf("%s: %d %v", kind, report.Counts[kind], report.Samples[kind])
+	}
+
+	if report.Repair != nil {
+		for _, failure := range report.Repair.Failures {
+			log.Printf("документ %s не исправлен: [%d] %s", failure.DocumentID, failure.Status, failure.Reason)
+		}
+		log.Printf("Исправлено: проиндексировано %d, удалено %d, ошибок %d",
+			report.Repair.Indexed, report.Repair.Deleted, report.Repair.Failed)
+		if report.Repair.Failed > 0 {
+			os.Exit(1)
+		}
+		return
+	}
+
+	if !report.Consistent() {
+		os.Exit(1)
+	}
+	log.Println("Индекс соответствует БД")
+}
@@ -0,0 +1,49 @@
+package main
+
+// Этот файл запускает relay транзакционного outbox: события об изменении объявлений, записанные
+// в таблицу advertisement_outbox вместе с самими изменениями, публикуются в KAFKA_ADVERTISEMENTS_TOPIC.
+//
+// Шаги, выполняемые в этом файле:
+//   1. Устанавливается соединение с PostgreSQL и создаётся Kafka producer.
+//   2. Неотправленные записи outbox блокируются пакетами по -batch-size в порядке id и публикуются
+//      с ключом, равным id объявления.
+//   3. После подтверждения доставки записи отмечаются отправленными (sent_at) в той же транзакции.
+//   4. Когда outbox пуст, relay ждёт -poll-interval и проверяет снова.
+//
+// Пример запуска:
+//   go run main.go -batch-size=500 -poll-interval=1s
+//
+// События читает индексатор cmd/indexer-consumer. Работа завершается по SIGINT/SIGTERM.
+
+import (
+	"SearchService/config/server"
+	"SearchService/internal/outbox"
+	"SearchService/internal/repository"
+	"context"
+	"flag"
+	"log"
+	"os/signal"
+	"syscall"
+)
+
+func main() {
+	batchSize := flag.Int("batch-size", outbox.DefaultOptions.BatchSize, "Сколько записей outbox публиковать за один проход")
+	pollInterval := flag.Duration("poll-interval", outbox.DefaultOptions.PollInterval, "Пауза между проверками пустого outbox")
+	flag.Parse()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	database := server.SetupDatabase()
+	defer database.Close()
+
+	producer := server.SetupKafkaProducer()
+	defer producer.Close()
+
+	relay := outbox.NewRelay(repository.NewOutboxRepository(database), producer, server.KafkaAdvertisementsTopic,
+		outbox.Options{BatchSize: *batchSize, PollInterval: *pollInterval})
+
+	log.Printf("Relay outbox запущен, публикация в %s", server.KafkaAdvertisementsTopic)
+	relay.Run(ctx)
+	log.Println("Relay outbox остановлен")
+}
@@ -0,0 +1,15 @@
+package kafka
+
+import "github.com/confluentinc/confluent-kafka-go/kafka"
+
+type ProducerConfig struct {
+	Brokers string `yaml:"brokers"`
+}
+
+func NewKafkaProducer(cfg ProducerConfig) (*kafka.Producer, error) {
+	return kafka.NewProducer(&kafka.ConfigMap{
+		"bootstrap.servers":  cfg.Brokers,
+		"acks":               "all",
+		"enable.idempotence": true, // Повтор отправки не создаёт дублей
+	})
+}
@@ -2,8 +2,14 @@ package server
 
 import (
 	elasticsearch2 "SearchService/config/elasticsearch"
+	kafka2 "SearchService/config/kafka"
 	"SearchService/internal"
+	"SearchService/internal/consumer"
+	"SearchService/internal/model"
+	"SearchService/internal/repository"
+	"SearchService/internal/util"
 	protobuf "SearchService/proto/your/module/path/proto"
+	"github.com/confluentinc/confluent-kafka-go/kafka"
 	"github.com/elastic/go-elasticsearch/v8"
 	"github.com/go-chi/chi/v5"
 	"github.com/joho/godotenv"
@@ -14,17 +20,28 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
 )
 
 var (
-	DbDriverName           string
-	DbConnectionString     string
-	ServerAddress          string
-	ElasticsearchAddresses string
-	ElasticsearchUsername  string
-	ElasticsearchPassword  string
-	GrpcNetwork            string
-	GrpcAddress            string
+	DbDriverName             string
+	DbConnectionString       string
+	ServerAddress            string
+	ElasticsearchAddresses   string
+	ElasticsearchUsername    string
+	ElasticsearchPassword    string
+	GrpcNetwork              string
+	GrpcAddress              string
+	SearchFieldBoosts        string
+	KafkaBrokers             string
+	KafkaGroupID             string
+	KafkaAdvertisementsTopic string
+	KafkaAutoOffsetReset     string
+	KafkaDeadLetterTopic     string
+	KafkaMaxAttempts         string
+	KafkaEventFormat         string
+	ImportDirectory          string
+	CSVColumnAliases         string
 )
 
 type gRPCServer struct {
@@ -51,6 +68,16 @@ func init() {
 	ServerAddress = os.Getenv("SERVER_ADDRESS")
 	GrpcNetwork = os.Getenv("GRPC_NETWORK")
 	GrpcAddress = os.Getenv("GRPC_ADDRESS")
+	SearchFieldBoosts = os.Getenv("SEARCH_FIELD_BOOSTS")
+	KafkaBrokers = os.Getenv("KAFKA_BROKERS")
+	KafkaGroupID = os.Getenv("KAFKA_GROUP_ID")
+	KafkaAdvertisementsTopic = os.Getenv("KAFKA_ADVERTISEMENTS_TOPIC")
+	KafkaAutoOffsetReset = os.Getenv("KAFKA_AUTO_OFFSET_RESET")
+	KafkaDeadLetterTopic = os.Getenv("KAFKA_DEAD_LETTER_TOPIC")
+	KafkaMaxAttempts = os.Getenv("KAFKA_MAX_ATTEMPTS")
+	KafkaEventFormat = os.Getenv("KAFKA_EVENT_FORMAT")
+	ImportDirectory = os.Getenv("IMPORT_DIRECTORY")
+	CSVColumnAliases = os.Getenv("CSV_COLUMN_ALIASES")
 }
 
 func SetupDatabase() *internal.Database {
@@ -78,6 +105,106 @@ func SetupElasticSearch() *elasticsearch.Client {
 	return esClient
 }
 
+// SetupSearchFieldBoosts возвращает веса полей для полнотекстового поиска из SEARCH_FIELD_BOOSTS.
+// Если переменная не задана, используются веса по умолчанию.
+func SetupSearchFieldBoosts() repository.FieldBoosts {
+	if SearchFieldBoosts == "" {
+		return repository.DefaultFieldBoosts
+	}
+
+	boosts, err := repository.ParseFieldBoosts(SearchFieldBoosts)
+	if err != nil {
+		log.Fatalf("ошибка чтения SEARCH_FIELD_BOOSTS: %s", err)
+	}
+
+	return boosts
+}
+
+// SetupKafkaConsumer подписывается на топик событий об изменении объявлений.
+// Offset'ы сохраняются вручную после обработки и периодически коммитятся автоматически.
+func SetupKafkaConsumer() *kafka.Consumer {
+	return SetupKafkaTopicConsumer(KafkaAdvertisementsTopic, KafkaGroupID)
+}
+
+// SetupKafkaTopicConsumer подписывает группу groupID на topic с ручным сохранением offset'ов
+func SetupKafkaTopicConsumer(topic string, groupID string) *kafka.Consumer {
+	autoOffsetReset := KafkaAutoOffsetReset
+	if autoOffsetReset == "" {
+		autoOffsetReset = "earliest"
+	}
+
+	consumer, err := kafka2.NewKafkaConsumer(kafka2.ConsumerGroupConfig{
+		Brokers:          KafkaBrokers,
+		GroupID:          groupID,
+		Topic:            topic,
+		AutoOffsetReset:  autoOffsetReset,
+		EnableAutoCommit: true,
+	})
+	if err != nil {
+		log.Fatalf("ошибка инициализации Kafka: %s", err)
+	}
+	log.Printf("Kafka: подписка на %s (группа %s)", topic, groupID)
+
+	return consumer
+}
+
+func SetupKafkaProducer() *kafka.Producer {
+	producer, err := kafka2.NewKafkaProducer(kafka2.ProducerConfig{Brokers: KafkaBrokers})
+	if err != nil {
+		log.Fatalf("ошибка инициализации Kafka producer: %s", err)
+	}
+
+	return producer
+}
+
+// SetupConsumerOptions возвращает настройки обработки событий: число попыток из KAFKA_MAX_ATTEMPTS,
+// топик недоставленных сообщений из KAFKA_DEAD_LETTER_TOPIC и формат событий из KAFKA_EVENT_FORMAT
+func SetupConsumerOptions() consumer.Options {
+	if KafkaDeadLetterTopic == "" {
+		log.Fatal("не задан KAFKA_DEAD_LETTER_TOPIC")
+	}
+
+	format, err := model.ParseEventFormat(KafkaEventFormat)
+	if err != nil {
+		log.Fatalf("ошибка чтения KAFKA_EVENT_FORMAT: %s", err)
+	}
+
+	options := consumer.Options{DeadLetterTopic: KafkaDeadLetterTopic, Format: format}
+	if KafkaMaxAttempts != "" {
+		maxAttempts, err := strconv.Atoi(KafkaMaxAttempts)
+		if err != nil || maxAttempts <= 0 {
+			log.Fatalf("неверное значение KAFKA_MAX_ATTEMPTS: %s", KafkaMaxAttempts)
+		}
+		options.MaxAttempts = maxAttempts
+	}
+
+	return options
+}
+
+// SetupCSVColumnAliases возвращает названия колонок CSV для импорта: стандартные и дополнительные из CSV_COLUMN_ALIASES
+func SetupCSVColumnAliases() util.CSVColumnAliases {
+	aliases, err := util.ParseCSVColumnAliases(CSVColumnAliases)
+	if err != nil {
+		log.Fatalf("ошибка чтения CSV_COLUMN_ALIASES: %s", err)
+	}
+
+	return aliases
+}
+
+// SetupImportDirectory создаёт каталог для загруженных CSV-файлов. Если IMPORT_DIRECTORY не задан,
+// используется подкаталог временного каталога ОС, который может не пережить перезагрузку машины.
+func SetupImportDirectory() string {
+	directory := ImportDirectory
+	if directory == "" {
+		directory = filepath.Join(os.TempDir(), "search-service-imports")
+	}
+	if err := os.MkdirAll(directory, 0o750); err != nil {
+		log.Fatalf("ошибка создания каталога импорта %s: %v", directory, err)
+	}
+
+	return directory
+}
+
 func SetupRestServer() (*http.Server, *chi.Mux) {
 	router := chi.NewRouter()
 
@@ -87,6 +214,7 @@ func SetupRestServer() (*http.Server, *chi.Mux) {
 	}, router
 }
 
+// RunGRPCServer запускает gRPC-сервер и блокируется до его остановки
 func RunGRPCServer() {
 	listener, err := net.Listen(GrpcNetwork, GrpcAddress)
 	if err != nil {
@@ -95,4 +223,9 @@ func RunGRPCServer() {
 
 	server := grpc.NewServer()
 	protobuf.RegisterHelloServiceServer(server, &gRPCServer{})
+
+	log.Println("gRPC-сервер запущен на " + listener.Addr().String())
+	if err := server.Serve(listener); err != nil {
+		log.Fatalf("ошибка работы gRPC-сервера: %v", err)
+	}
 }
@@ -0,0 +1,184 @@
+package consumer
+
+// advertisement_consumer содержит инкрементальную индексацию: события об изменении объявлений
+// читаются из Kafka и сразу применяются к индексу. Offset сообщения сохраняется только после
+// успешной записи в Elasticsearch, поэтому после перезапуска необработанные события читаются заново.
+// Событие, которое не удалось разобрать или применить за Options.MaxAttempts попыток, публикуется
+// в DLQ, чтобы не блокировать партицию. Если и DLQ недоступен дольше deadLetterTimeout, обработка
+// останавливается с ошибкой, не сохраняя offset события, и после перезапуска оно читается заново.
+
+import (
+	"SearchService/internal/model"
+	"SearchService/internal/ports"
+	"SearchService/internal/util"
+	"context"
+	"fmt"
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/elastic/go-elasticsearch/v8"
+	"log"
+	"time"
+)
+
+const (
+	// pollTimeout — сколько ждать сообщение, прежде чем проверить остановку
+	pollTimeout = time.Second
+	// initialRetryBackoff и maxRetryBackoff ограничивают паузу между попытками применить событие
+	initialRetryBackoff = time.Second
+	maxRetryBackoff     = 30 * time.Second
+	// deadLetterTimeout ограничивает публикацию в DLQ вместе с повторами. Он должен быть заметно меньше
+	// max.poll.interval.ms consumer'а (5 минут) с учётом повторов применения события, иначе
+	// группа исключит consumer'а раньше, чем обработка остановится.
+	deadLetterTimeout = time.Minute
+	// DefaultMaxAttempts используется, если Options.MaxAttempts не задан
+	DefaultMaxAttempts = 5
+)
+
+// Options — настройки обработки событий
+type Options struct {
+	// MaxAttempts — сколько раз пытаться применить событие, прежде чем отправить его в DLQ
+	MaxAttempts int
+	// DeadLetterTopic — топик для событий, которые не удалось обработать
+	DeadLetterTopic string
+	// Format — формат сообщений: события приложения или CDC-события Debezium
+	Format model.EventFormat
+}
+
+type AdvertisementConsumer struct {
+	consumer *kafka.Consumer
+	producer messageProducer
+	esClient *elasticsearch.Client
+	loader   ports.AdvertisementBatchLoader
+	options  Options
+	// retryBackoff — первая пауза между попытками применить событие или опубликовать его в DLQ
+	retryBackoff time.Duration
+	// deadLetterTimeout — сколько всего пытаться опубликовать событие в DLQ
+	deadLetterTimeout time.Duration
+}
+
+func NewAdvertisementConsumer(consumer *kafka.Consumer, producer *kafka.Producer, esClient *elasticsearch.Client,
+	loader ports.AdvertisementBatchLoader, options Options) *AdvertisementConsumer {
+	if options.MaxAttempts <= 0 {
+		options.MaxAttempts = DefaultMaxAttempts
+	}
+	if options.Format == "" {
+		options.Format = model.EventFormatNative
+	}
+
+	return &AdvertisementConsumer{consumer: consumer, producer: producer, esClient: esClient, loader: loader, options: options,
+		retryBackoff: initialRetryBackoff, deadLetterTimeout: deadLetterTimeout}
+}
+
+// Run читает события до отмены ctx. Ошибка возвращается при фатальной ошибке Kafka или если событие
+// не удалось опубликовать в DLQ; offset такого события не сохраняется.
+func (c *AdvertisementConsumer) Run(ctx context.Context) error {
+	for ctx.Err() == nil {
+		message, err := c.consumer.ReadMessage(pollTimeout)
+		if err != nil {
+			kafkaErr, ok := err.(kafka.Error)
+			if ok && kafkaErr.Code() == kafka.ErrTimedOut {
+				continue
+			}
+			if ok && kafkaErr.IsFatal() {
+				return fmt.Errorf("фатальная ошибка Kafka: %w", err)
+			}
+			log.Printf("ошибка чтения из Kafka: %v", err)
+			continue
+		}
+
+		if err := c.handle(ctx, message); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		if _, err := c.consumer.StoreMessage(message); err != nil {
+			log.Printf("ошибка сохранения offset %s: %v", message.TopicPartition, err)
+		}
+	}
+
+	return nil
+}
+
+// handle применяет событие из message не более options.MaxAttempts раз, а при неудаче отправляет его в DLQ.
+// Ошибка означает, что событие не обработано (остановлен ctx или недоступен DLQ) и offset сохранять нельзя.
+func (c *AdvertisementConsumer) handle(ctx context.Context, message *kafka.Message) error {
+	event, err := c.decode(message)
+	if err != nil {
+		// Повтор не исправит неразбираемое сообщение, поэтому оно сразу уходит в DLQ
+		return c.deadLetter(ctx, message, err, 1)
+	}
+
+	backoff := c.retryBackoff
+	for attempt := 1; ; attempt++ {
+		err := util.ApplyAdvertisementEvent(c.esClient, c.loader, event)
+		if err == nil {
+			return nil
+		}
+
+		if attempt >= c.options.MaxAttempts {
+			return c.deadLetter(ctx, message, err, attempt)
+		}
+
+		log.Printf("ошибка применения события %s (%s id=%d), попытка %d из %d, повтор через %s: %v",
+			message.TopicPartition, event.Type, event.ID, attempt, c.options.MaxAttempts, backoff, err)
+
+		if !sleep(ctx, backoff) {
+			return ctx.Err()
+		}
+		backoff = nextBackoff(backoff)
+	}
+}
+
+// decode разбирает сообщение в формате options.Format
+func (c *AdvertisementConsumer) decode(message *kafka.Message) (model.AdvertisementEvent, error) {
+	if c.options.Format == model.EventFormatDebezium {
+		return model.ParseDebeziumEvent(message.Key, message.Value)
+	}
+	return model.ParseAdvertisementEvent(message.Value)
+}
+
+// deadLetter публикует исходное сообщение в DLQ, повторяя попытки не дольше deadLetterTimeout.
+// Если публикация так и не удалась, возвращается ошибка: offset не сохраняется, чтобы событие не потерялось.
+func (c *AdvertisementConsumer) deadLetter(ctx context.Context, message *kafka.Message, cause error, attempts int) error {
+	log.Printf("событие %s отправляется в %s после %d попыток: %v", message.TopicPartition, c.options.DeadLetterTopic, attempts, cause)
+
+	deadlineCtx, cancel := context.WithTimeout(ctx, c.deadLetterTimeout)
+	defer cancel()
+
+	deadLetter := deadLetterMessage(message, c.options.DeadLetterTopic, cause, attempts, time.Now())
+	backoff := c.retryBackoff
+	for {
+		err := produceSync(deadlineCtx, c.producer, deadLetter)
+		if err == nil {
+			return nil
+		}
+
+		log.Printf("ошибка публикации в DLQ, повтор через %s: %v", backoff, err)
+		if !sleep(deadlineCtx, backoff) {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("событие %s не опубликовано в %s за %s: %w", message.TopicPartition, c.options.DeadLetterTopic, c.deadLetterTimeout, err)
+		}
+		backoff = nextBackoff(backoff)
+	}
+}
+
+// sleep ждёт delay и возвращает false, если ctx отменён раньше
+func sleep(ctx context.Context, delay time.Duration) bool {
+	select {
+	case <-time.After(delay):
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func nextBackoff(backoff time.Duration) time.Duration {
+	backoff *= 2
+	if backoff > maxRetryBackoff {
+		return maxRetryBackoff
+	}
+	return backoff
+}
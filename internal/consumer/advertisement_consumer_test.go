@@ -0,0 +1,193 @@
+package consumer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/elastic/go-elasticsearch/v8"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// failingTransport отвечает на каждый запрос к Elasticsearch ошибкой 503 и считает запросы
+type failingTransport struct {
+	mu       sync.Mutex
+	requests int
+}
+
+func (transport *failingTransport) RoundTrip(*http.Request) (*http.Response, error) {
+	transport.mu.Lock()
+	transport.requests++
+	transport.mu.Unlock()
+
+	return &http.Response{
+		StatusCode: http.StatusServiceUnavailable,
+		Header:     http.Header{"X-Elastic-Product": []string{"Elasticsearch"}, "Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(bytes.NewReader([]byte(`{"error":"unavailable"}`))),
+	}, nil
+}
+
+// recordingProducer запоминает отправленные сообщения. Если err задан, Produce возвращает его,
+// если silent — подтверждение доставки не приходит никогда.
+type recordingProducer struct {
+	messages []*kafka.Message
+	err      error
+	silent   bool
+}
+
+func (producer *recordingProducer) Produce(message *kafka.Message, deliveryChan chan kafka.Event) error {
+	producer.messages = append(producer.messages, message)
+	if producer.err != nil {
+		return producer.err
+	}
+	if !producer.silent {
+		deliveryChan <- message
+	}
+	return nil
+}
+
+func testConsumer(t *testing.T, producer messageProducer) (*AdvertisementConsumer, *failingTransport) {
+	t.Helper()
+	transport := &failingTransport{}
+	esClient, err := elasticsearch.NewClient(elasticsearch.Config{Transport: transport, MaxRetries: 0, DisableRetry: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	consumer := NewAdvertisementConsumer(nil, nil, esClient, nil, Options{MaxAttempts: 3, DeadLetterTopic: "advertisements.changes.dlq"})
+	consumer.producer = producer
+	consumer.retryBackoff = time.Millisecond
+	consumer.deadLetterTimeout = 50 * time.Millisecond
+	return consumer, transport
+}
+
+func sourceMessage(value string) *kafka.Message {
+	topic := "advertisements.changes"
+	return &kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: 2, Offset: 17},
+		Key:            []byte("7"),
+		Value:          []byte(value),
+		Headers:        []kafka.Header{{Key: "trace-id", Value: []byte("abc")}},
+	}
+}
+
+func TestHandleSendsEventToDeadLetterAfterMaxAttempts(t *testing.T) {
+	tests := []struct {
+		name         string
+		value        string
+		wantAttempts string
+		wantRequests int
+	}{
+		{
+			name:         "elasticsearch keeps failing",
+			value:        `{"type":"update","id":7,"advertisement":{"product_name":"Product"}}`,
+			wantAttempts: "3",
+			wantRequests: 3,
+		},
+		{
+			// Неразбираемое сообщение уходит в DLQ сразу, без обращений к индексу
+			name:         "malformed message",
+			value:        `{"type":`,
+			wantAttempts: "1",
+			wantRequests: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			producer := &recordingProducer{}
+			consumer, transport := testConsumer(t, producer)
+
+			if err := consumer.handle(context.Background(), sourceMessage(tt.value)); err != nil {
+				t.Fatalf("handle() error = %v", err)
+			}
+
+			if transport.requests != tt.wantRequests {
+				t.Errorf("elasticsearch requests = %d, want %d", transport.requests, tt.wantRequests)
+			}
+			if len(producer.messages) != 1 {
+				t.Fatalf("produced %d messages, want 1", len(producer.messages))
+			}
+
+			deadLetter := producer.messages[0]
+			if *deadLetter.TopicPartition.Topic != "advertisements.changes.dlq" {
+				t.Errorf("topic = %s, want advertisements.changes.dlq", *deadLetter.TopicPartition.Topic)
+			}
+			if string(deadLetter.Key) != "7" || string(deadLetter.Value) != tt.value {
+				t.Errorf("key/value = %s/%s, want original", deadLetter.Key, deadLetter.Value)
+			}
+
+			headers := make(map[string]string)
+			for _, header := range deadLetter.Headers {
+				headers[header.Key] = string(header.Value)
+			}
+			wantHeaders := map[string]string{
+				"trace-id":            "abc",
+				HeaderAttempts:        tt.wantAttempts,
+				HeaderSourceTopic:     "advertisements.changes",
+				HeaderSourcePartition: "2",
+				HeaderSourceOffset:    "17",
+			}
+			for key, want := range wantHeaders {
+				if headers[key] != want {
+					t.Errorf("header %s = %q, want %q", key, headers[key], want)
+				}
+			}
+			if headers[HeaderError] == "" {
+				t.Errorf("header %s is empty", HeaderError)
+			}
+			if _, err := time.Parse(time.RFC3339, headers[HeaderFailedAt]); err != nil {
+				t.Errorf("header %s = %q is not RFC 3339: %v", HeaderFailedAt, headers[HeaderFailedAt], err)
+			}
+		})
+	}
+}
+
+func TestHandleFailsWhenDeadLetterIsUnavailable(t *testing.T) {
+	tests := []struct {
+		name     string
+		producer *recordingProducer
+	}{
+		{name: "produce rejected", producer: &recordingProducer{err: errors.New("queue full")}},
+		{name: "delivery not confirmed", producer: &recordingProducer{silent: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			consumer, _ := testConsumer(t, tt.producer)
+
+			started := time.Now()
+			err := consumer.handle(context.Background(), sourceMessage(`{"type":`))
+			if err == nil {
+				t.Fatal("handle() error = nil, want dead letter failure")
+			}
+			if !strings.Contains(err.Error(), "advertisements.changes.dlq") {
+				t.Errorf("handle() error = %v, want it to name the dead letter topic", err)
+			}
+			if elapsed := time.Since(started); elapsed > time.Second {
+				t.Errorf("handle() took %s, want it bounded by deadLetterTimeout", elapsed)
+			}
+			if len(tt.producer.messages) == 0 {
+				t.Error("no dead letter publication attempted")
+			}
+		})
+	}
+}
+
+func TestHandleStopsOnCancel(t *testing.T) {
+	consumer, _ := testConsumer(t, &recordingProducer{})
+	consumer.retryBackoff = time.Hour
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := consumer.handle(ctx, sourceMessage(`{"type":"update","id":7,"advertisement":{"product_name":"Product"}}`))
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("handle() error = %v, want context.Canceled", err)
+	}
+}
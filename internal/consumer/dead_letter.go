@@ -0,0 +1,170 @@
+package consumer
+
+// dead_letter содержит работу с топиком недоставленных сообщений (DLQ): событие, которое не удалось
+// применить за отведённое число попыток, публикуется туда без изменений вместе с заголовками о причине
+// отказа, а DeadLetterReplayer возвращает такие события в исходный топик после исправления ошибки
+
+import (
+	"context"
+	"fmt"
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Заголовки, которые добавляются к сообщению при публикации в DLQ
+const (
+	HeaderError           = "dlq.error"
+	HeaderAttempts        = "dlq.attempts"
+	HeaderSourceTopic     = "dlq.source.topic"
+	HeaderSourcePartition = "dlq.source.partition"
+	HeaderSourceOffset    = "dlq.source.offset"
+	HeaderFailedAt        = "dlq.failed.at"
+)
+
+// deadLetterHeaderPrefix — общий префикс заголовков DLQ, по нему они убираются при повторной отправке
+const deadLetterHeaderPrefix = "dlq."
+
+// deadLetterMessage формирует сообщение для DLQ: исходные ключ, значение и заголовки
+// и сведения об ошибке, числе попыток и положении исходного сообщения
+func deadLetterMessage(source *kafka.Message, topic string, cause error, attempts int, failedAt time.Time) *kafka.Message {
+	headers := make([]kafka.Header, 0, len(source.Headers)+6)
+	headers = append(headers, source.Headers...)
+
+	sourceTopic := ""
+	if source.TopicPartition.Topic != nil {
+		sourceTopic = *source.TopicPartition.Topic
+	}
+
+	headers = append(headers,
+		kafka.Header{Key: HeaderError, Value: []byte(cause.Error())},
+		kafka.Header{Key: HeaderAttempts, Value: []byte(strconv.Itoa(attempts))},
+		kafka.Header{Key: HeaderSourceTopic, Value: []byte(sourceTopic)},
+		kafka.Header{Key: HeaderSourcePartition, Value: []byte(strconv.Itoa(int(source.TopicPartition.Partition)))},
+		kafka.Header{Key: HeaderSourceOffset, Value: []byte(strconv.FormatInt(int64(source.TopicPartition.Offset), 10))},
+		kafka.Header{Key: HeaderFailedAt, Value: []byte(failedAt.UTC().Format(time.RFC3339))},
+	)
+
+	return &kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: kafka.PartitionAny},
+		Key:            source.Key,
+		Value:          source.Value,
+		Headers:        headers,
+	}
+}
+
+// replayMessage восстанавливает исходное сообщение из сообщения DLQ.
+// Сообщение отправляется в targetTopic, а если он не задан — в топик из заголовка dlq.source.topic.
+func replayMessage(deadLetter *kafka.Message, targetTopic string) (*kafka.Message, error) {
+	topic := targetTopic
+	var headers []kafka.Header
+	for _, header := range deadLetter.Headers {
+		if header.Key == HeaderSourceTopic && targetTopic == "" {
+			topic = string(header.Value)
+		}
+		if !strings.HasPrefix(header.Key, deadLetterHeaderPrefix) {
+			headers = append(headers, header)
+		}
+	}
+
+	if topic == "" {
+		return nil, fmt.Errorf("не удалось определить исходный топик сообщения %s", deadLetter.TopicPartition)
+	}
+
+	return &kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: kafka.PartitionAny},
+		Key:            deadLetter.Key,
+		Value:          deadLetter.Value,
+		Headers:        headers,
+	}, nil
+}
+
+// messageProducer — отправка сообщений в Kafka, реализуется *kafka.Producer
+type messageProducer interface {
+	Produce(message *kafka.Message, deliveryChan chan kafka.Event) error
+}
+
+// produceSync отправляет сообщение и ждёт подтверждения доставки, но не дольше, чем живёт ctx
+func produceSync(ctx context.Context, producer messageProducer, message *kafka.Message) error {
+	// Буфер на одно событие: подтверждение, пришедшее после отмены ctx, не блокирует producer
+	deliveries := make(chan kafka.Event, 1)
+	if err := producer.Produce(message, deliveries); err != nil {
+		return fmt.Errorf("ошибка отправки в %s: %w", *message.TopicPartition.Topic, err)
+	}
+
+	var event kafka.Event
+	select {
+	case event = <-deliveries:
+	case <-ctx.Done():
+		return fmt.Errorf("нет подтверждения доставки в %s: %w", *message.TopicPartition.Topic, ctx.Err())
+	}
+
+	delivered, ok := event.(*kafka.Message)
+	if !ok {
+		return fmt.Errorf("неожиданный ответ при отправке в %s", *message.TopicPartition.Topic)
+	}
+	if delivered.TopicPartition.Error != nil {
+		return fmt.Errorf("ошибка доставки в %s: %w", *message.TopicPartition.Topic, delivered.TopicPartition.Error)
+	}
+
+	return nil
+}
+
+// DeadLetterReplayer возвращает сообщения из DLQ в исходный топик
+type DeadLetterReplayer struct {
+	consumer *kafka.Consumer
+	producer *kafka.Producer
+	// targetTopic, если задан, заменяет исходный топик из заголовков
+	targetTopic string
+}
+
+func NewDeadLetterReplayer(consumer *kafka.Consumer, producer *kafka.Producer, targetTopic string) *DeadLetterReplayer {
+	return &DeadLetterReplayer{consumer: consumer, producer: producer, targetTopic: targetTopic}
+}
+
+// Run переотправляет сообщения, пока DLQ не будет вычитан (нет новых сообщений дольше idleTimeout),
+// не будет переотправлено limit сообщений (0 — без ограничения) или не будет отменён ctx.
+// Offset сообщения DLQ сохраняется только после подтверждённой доставки в исходный топик.
+func (r *DeadLetterReplayer) Run(ctx context.Context, limit int, idleTimeout time.Duration) (int, error) {
+	replayed := 0
+	idleSince := time.Now()
+
+	for ctx.Err() == nil && (limit <= 0 || replayed < limit) {
+		message, err := r.consumer.ReadMessage(pollTimeout)
+		if err != nil {
+			kafkaErr, ok := err.(kafka.Error)
+			if ok && kafkaErr.Code() == kafka.ErrTimedOut {
+				if time.Since(idleSince) >= idleTimeout {
+					break
+				}
+				continue
+			}
+			if ok && kafkaErr.IsFatal() {
+				return replayed, fmt.Errorf("фатальная ошибка Kafka: %w", err)
+			}
+			log.Printf("ошибка чтения из Kafka: %v", err)
+			continue
+		}
+		idleSince = time.Now()
+
+		original, err := replayMessage(message, r.targetTopic)
+		if err != nil {
+			return replayed, err
+		}
+		// Доставка дожидается и после отмены ctx: сообщение уже передано producer'у,
+		// а его подтверждение в любом случае придёт не позже message.timeout.ms
+		if err := produceSync(context.Background(), r.producer, original); err != nil {
+			return replayed, err
+		}
+		if _, err := r.consumer.StoreMessage(message); err != nil {
+			return replayed, fmt.Errorf("ошибка сохранения offset %s: %w", message.TopicPartition, err)
+		}
+
+		replayed++
+		log.Printf("сообщение %s возвращено в %s", message.TopicPartition, *original.TopicPartition.Topic)
+	}
+
+	return replayed, nil
+}
@@ -0,0 +1,65 @@
+package consumer
+
+import (
+	"errors"
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDeadLetterMessageRoundTrip(t *testing.T) {
+	sourceTopic := "advertisements.changes"
+	source := &kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &sourceTopic, Partition: 3, Offset: 42},
+		Key:            []byte("7"),
+		Value:          []byte(`{"type":`),
+		Headers:        []kafka.Header{{Key: "trace-id", Value: []byte("abc")}},
+	}
+
+	deadLetter := deadLetterMessage(source, "advertisements.changes.dlq", errors.New("boom"), 5,
+		time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC))
+
+	if *deadLetter.TopicPartition.Topic != "advertisements.changes.dlq" {
+		t.Errorf("topic = %s, want advertisements.changes.dlq", *deadLetter.TopicPartition.Topic)
+	}
+
+	headers := make(map[string]string)
+	for _, header := range deadLetter.Headers {
+		headers[header.Key] = string(header.Value)
+	}
+	wantHeaders := map[string]string{
+		"trace-id":            "abc",
+		HeaderError:           "boom",
+		HeaderAttempts:        "5",
+		HeaderSourceTopic:     sourceTopic,
+		HeaderSourcePartition: "3",
+		HeaderSourceOffset:    "42",
+		HeaderFailedAt:        "2025-01-02T03:04:05Z",
+	}
+	if !reflect.DeepEqual(headers, wantHeaders) {
+		t.Errorf("headers = %v, want %v", headers, wantHeaders)
+	}
+
+	replayed, err := replayMessage(deadLetter, "")
+	if err != nil {
+		t.Fatalf("replayMessage() error = %v", err)
+	}
+	if *replayed.TopicPartition.Topic != sourceTopic {
+		t.Errorf("replayed topic = %s, want %s", *replayed.TopicPartition.Topic, sourceTopic)
+	}
+	if string(replayed.Key) != "7" || string(replayed.Value) != `{"type":` {
+		t.Errorf("replayed key/value = %s/%s, want original", replayed.Key, replayed.Value)
+	}
+	if !reflect.DeepEqual(replayed.Headers, source.Headers) {
+		t.Errorf("replayed headers = %v, want %v", replayed.Headers, source.Headers)
+	}
+
+	overridden, err := replayMessage(deadLetter, "advertisements.retry")
+	if err != nil {
+		t.Fatalf("replayMessage() error = %v", err)
+	}
+	if *overridden.TopicPartition.Topic != "advertisements.retry" {
+		t.Errorf("overridden topic = %s, want advertisements.retry", *overridden.TopicPartition.Topic)
+	}
+}
@@ -0,0 +1,235 @@
+package REST
+
+import (
+	"SearchService/internal/model"
+	"SearchService/internal/ports"
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+const (
+	// maxAdvertisementBodySize — максимальный размер тела запроса с объявлением
+	maxAdvertisementBodySize = 1 << 20
+	// IndexStatusHeader — заголовок ответа, который выставляется, если изменение зафиксировано в БД,
+	// но ещё не попало в поисковый индекс; значение — IndexStatusPending
+	IndexStatusHeader  = "X-Index-Status"
+	IndexStatusPending = "pending"
+)
+
+// AdvertisementHandler — CRUD объявлений. Источник истины — БД: изменение сначала фиксируется там
+// вместе с событием в outbox, затем документ сразу обновляется в индексе. Если обновить индекс
+// не удалось, запрос всё равно успешен: изменение сохранено, и индекс догонит БД по событию из outbox,
+// которое relay опубликует, а индексатор применит. До этого изменение не видно в поиске, о чём ответ
+// сообщает заголовком X-Index-Status: pending.
+type AdvertisementHandler struct {
+	store   ports.AdvertisementStore
+	indexer ports.AdvertisementIndexer
+}
+
+func NewAdvertisementHandler(store ports.AdvertisementStore, indexer ports.AdvertisementIndexer) *AdvertisementHandler {
+	return &AdvertisementHandler{store: store, indexer: indexer}
+}
+
+// Create — POST /advertisements
+func (handler *AdvertisementHandler) Create(writer http.ResponseWriter, request *http.Request) {
+	var advertisement model.Advertisement
+	if err := decodeAdvertisement(writer, request, &advertisement); err != nil {
+		http.Error(writer, "Некорректное тело запроса: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if advertisement.Index != 0 {
+		http.Error(writer, "id назначается сервером и не передаётся при создании", http.StatusBadRequest)
+		return
+	}
+	if err := advertisement.Validate(); err != nil {
+		http.Error(writer, "Некорректное объявление: "+err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	if _, err := handler.store.Save(&advertisement); err != nil {
+		http.Error(writer, "Ошибка сохранения объявления: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	handler.index(writer, &advertisement)
+
+	writer.Header().Set("Location", "/advertisements/"+strconv.Itoa(advertisement.Index))
+	writeAdvertisement(writer, http.StatusCreated, &advertisement)
+}
+
+// Get — GET /advertisements/{id}
+func (handler *AdvertisementHandler) Get(writer http.ResponseWriter, request *http.Request) {
+	id, ok := parseAdvertisementID(writer, request)
+	if !ok {
+		return
+	}
+
+	advertisement, err := handler.store.GetAdvertisementById(id)
+	if err != nil {
+		writeStoreError(writer, id, err)
+		return
+	}
+
+	writeAdvertisement(writer, http.StatusOK, &advertisement)
+}
+
+// Replace — PUT /advertisements/{id}: объявление заменяется целиком
+func (handler *AdvertisementHandler) Replace(writer http.ResponseWriter, request *http.Request) {
+	id, ok := parseAdvertisementID(writer, request)
+	if !ok {
+		return
+	}
+
+	var advertisement model.Advertisement
+	if err := decodeAdvertisement(writer, request, &advertisement); err != nil {
+		http.Error(writer, "Некорректное тело запроса: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	handler.update(writer, id, &advertisement)
+}
+
+// Patch — PATCH /advertisements/{id}: изменяются только переданные поля.
+// Поля накладываются на объявление под блокировкой строки в хранилище, поэтому параллельные
+// PATCH одного объявления не затирают изменения друг друга.
+func (handler *AdvertisementHandler) Patch(writer http.ResponseWriter, request *http.Request) {
+	id, ok := parseAdvertisementID(writer, request)
+	if !ok {
+		return
+	}
+
+	// Тело читается до начала транзакции, чтобы блокировка строки не ждала медленного клиента
+	body, err := io.ReadAll(http.MaxBytesReader(writer, request.Body, maxAdvertisementBodySize))
+	if err != nil {
+		http.Error(writer, "Некорректное тело запроса: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	advertisement, err := handler.store.Patch(id, func(advertisement *model.Advertisement) error {
+		// Поля из тела накладываются на текущее объявление, отсутствующие остаются прежними
+		decoder := json.NewDecoder(bytes.NewReader(body))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(advertisement); err != nil {
+			return &requestError{status: http.StatusBadRequest, message: "Некорректное тело запроса: " + err.Error()}
+		}
+		return validateForUpdate(id, advertisement)
+	})
+
+	var invalid *requestError
+	if errors.As(err, &invalid) {
+		http.Error(writer, invalid.message, invalid.status)
+		return
+	}
+	if err != nil {
+		writeStoreError(writer, id, err)
+		return
+	}
+	handler.index(writer, advertisement)
+
+	writeAdvertisement(writer, http.StatusOK, advertisement)
+}
+
+// Delete — DELETE /advertisements/{id}
+func (handler *AdvertisementHandler) Delete(writer http.ResponseWriter, request *http.Request) {
+	id, ok := parseAdvertisementID(writer, request)
+	if !ok {
+		return
+	}
+
+	if err := handler.store.Delete(id); err != nil {
+		writeStoreError(writer, id, err)
+		return
+	}
+
+	if err := handler.indexer.DeleteAdvertisement(id); err != nil {
+		log.Printf("объявление %d удалено из БД, но не из индекса: %v", id, err)
+		writer.Header().Set(IndexStatusHeader, IndexStatusPending)
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+func (handler *AdvertisementHandler) update(writer http.ResponseWriter, id int, advertisement *model.Advertisement) {
+	var invalid *requestError
+	if err := validateForUpdate(id, advertisement); errors.As(err, &invalid) {
+		http.Error(writer, invalid.message, invalid.status)
+		return
+	}
+
+	if _, err := handler.store.Update(advertisement); err != nil {
+		writeStoreError(writer, id, err)
+		return
+	}
+	handler.index(writer, advertisement)
+
+	writeAdvertisement(writer, http.StatusOK, advertisement)
+}
+
+// requestError — ошибка в данных запроса с HTTP-статусом ответа
+type requestError struct {
+	status  int
+	message string
+}
+
+func (err *requestError) Error() string {
+	return err.message
+}
+
+// validateForUpdate проверяет объявление для записи по id: id в теле, если передан, должен совпадать с id в пути
+func validateForUpdate(id int, advertisement *model.Advertisement) error {
+	if advertisement.Index != 0 && advertisement.Index != id {
+		return &requestError{status: http.StatusBadRequest, message: "id в теле запроса не совпадает с id в пути"}
+	}
+	advertisement.Index = id
+
+	if err := advertisement.Validate(); err != nil {
+		return &requestError{status: http.StatusUnprocessableEntity, message: "Некорректное объявление: " + err.Error()}
+	}
+
+	return nil
+}
+
+// index обновляет документ в индексе. Ошибка не прерывает запрос, так как изменение уже зафиксировано в БД
+// и дойдёт до индекса через outbox; клиенту об отставании индекса сообщает заголовок X-Index-Status.
+func (handler *AdvertisementHandler) index(writer http.ResponseWriter, advertisement *model.Advertisement) {
+	if err := handler.indexer.IndexAdvertisement(advertisement); err != nil {
+		log.Printf("объявление %d сохранено в БД, но не проиндексировано: %v", advertisement.Index, err)
+		writer.Header().Set(IndexStatusHeader, IndexStatusPending)
+	}
+}
+
+func parseAdvertisementID(writer http.ResponseWriter, request *http.Request) (int, bool) {
+	id, err := strconv.Atoi(chi.URLParam(request, "id"))
+	if err != nil || id <= 0 {
+		http.Error(writer, "id объявления должен быть положительным числом", http.StatusBadRequest)
+		return 0, false
+	}
+	return id, true
+}
+
+// decodeAdvertisement читает JSON объявления из тела запроса, отклоняя неизвестные поля
+func decodeAdvertisement(writer http.ResponseWriter, request *http.Request, advertisement *model.Advertisement) error {
+	decoder := json.NewDecoder(http.MaxBytesReader(writer, request.Body, maxAdvertisementBodySize))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(advertisement)
+}
+
+func writeStoreError(writer http.ResponseWriter, id int, err error) {
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(writer, "Объявление "+strconv.Itoa(id)+" не найдено", http.StatusNotFound)
+		return
+	}
+	http.Error(writer, "Ошибка работы с объявлением: "+err.Error(), http.StatusInternalServerError)
+}
+
+func writeAdvertisement(writer http.ResponseWriter, status int, advertisement *model.Advertisement) {
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(status)
+	json.NewEncoder(writer).Encode(advertisement)
+}
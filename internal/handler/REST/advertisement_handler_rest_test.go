@@ -0,0 +1,234 @@
+package REST
+
+import (
+	"SearchService/internal/model"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// memoryStore — хранилище объявлений в памяти для тестов обработчика.
+// Patch выполняется под mu, как под блокировкой строки в БД.
+type memoryStore struct {
+	mu             sync.Mutex
+	advertisements map[int]model.Advertisement
+	nextID         int
+}
+
+func (store *memoryStore) Save(advertisement *model.Advertisement) (*model.Advertisement, error) {
+	store.nextID++
+	advertisement.Index = store.nextID
+	store.advertisements[advertisement.Index] = *advertisement
+	return advertisement, nil
+}
+
+func (store *memoryStore) GetAdvertisementById(id int) (model.Advertisement, error) {
+	advertisement, ok := store.advertisements[id]
+	if !ok {
+		return model.Advertisement{}, fmt.Errorf("объявление %d: %w", id, sql.ErrNoRows)
+	}
+	return advertisement, nil
+}
+
+func (store *memoryStore) Update(advertisement *model.Advertisement) (*model.Advertisement, error) {
+	if _, ok := store.advertisements[advertisement.Index]; !ok {
+		return nil, fmt.Errorf("объявление %d: %w", advertisement.Index, sql.ErrNoRows)
+	}
+	store.advertisements[advertisement.Index] = *advertisement
+	return advertisement, nil
+}
+
+func (store *memoryStore) Patch(id int, apply func(advertisement *model.Advertisement) error) (*model.Advertisement, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	advertisement, ok := store.advertisements[id]
+	if !ok {
+		return nil, fmt.Errorf("объявление %d: %w", id, sql.ErrNoRows)
+	}
+	if err := apply(&advertisement); err != nil {
+		return nil, err
+	}
+	store.advertisements[id] = advertisement
+	return &advertisement, nil
+}
+
+func (store *memoryStore) Delete(id int) error {
+	if _, ok := store.advertisements[id]; !ok {
+		return fmt.Errorf("объявление %d: %w", id, sql.ErrNoRows)
+	}
+	delete(store.advertisements, id)
+	return nil
+}
+
+// memoryIndexer запоминает документы, отправленные в индекс. Если задан err, индекс недоступен.
+type memoryIndexer struct {
+	mu        sync.Mutex
+	documents map[int]model.Advertisement
+	err       error
+}
+
+func (indexer *memoryIndexer) IndexAdvertisement(advertisement *model.Advertisement) error {
+	indexer.mu.Lock()
+	defer indexer.mu.Unlock()
+
+	if indexer.err != nil {
+		return indexer.err
+	}
+	indexer.documents[advertisement.Index] = *advertisement
+	return nil
+}
+
+func (indexer *memoryIndexer) DeleteAdvertisement(id int) error {
+	if indexer.err != nil {
+		return indexer.err
+	}
+	delete(indexer.documents, id)
+	return nil
+}
+
+func TestAdvertisementHandler(t *testing.T) {
+	store := &memoryStore{advertisements: map[int]model.Advertisement{}}
+	indexer := &memoryIndexer{documents: map[int]model.Advertisement{}}
+	handler := NewAdvertisementHandler(store, indexer)
+
+	router := chi.NewRouter()
+	router.Post("/advertisements", handler.Create)
+	router.Get("/advertisements/{id}", handler.Get)
+	router.Put("/advertisements/{id}", handler.Replace)
+	router.Patch("/advertisements/{id}", handler.Patch)
+	router.Delete("/advertisements/{id}", handler.Delete)
+
+	valid := `{"product_name":"Phone","brand":"Acme","category":"Electronics","price":10,"currency":"USD","stock":3,"availability":"in_stock"}`
+
+	steps := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		wantStatus int
+	}{
+		{name: "create", method: http.MethodPost, path: "/advertisements", body: valid, wantStatus: http.StatusCreated},
+		{name: "create invalid", method: http.MethodPost, path: "/advertisements", body: `{"product_name":"","price":-1}`, wantStatus: http.StatusUnprocessableEntity},
+		{name: "create unknown field", method: http.MethodPost, path: "/advertisements", body: `{"name":"Phone"}`, wantStatus: http.StatusBadRequest},
+		{name: "get", method: http.MethodGet, path: "/advertisements/1", wantStatus: http.StatusOK},
+		{name: "get missing", method: http.MethodGet, path: "/advertisements/2", wantStatus: http.StatusNotFound},
+		{name: "patch", method: http.MethodPatch, path: "/advertisements/1", body: `{"price":12.5}`, wantStatus: http.StatusOK},
+		{name: "patch invalid", method: http.MethodPatch, path: "/advertisements/1", body: `{"availability":"maybe"}`, wantStatus: http.StatusUnprocessableEntity},
+		{name: "patch malformed", method: http.MethodPatch, path: "/advertisements/1", body: `{"price":`, wantStatus: http.StatusBadRequest},
+		{name: "patch id mismatch", method: http.MethodPatch, path: "/advertisements/1", body: `{"id":5}`, wantStatus: http.StatusBadRequest},
+		{name: "patch missing", method: http.MethodPatch, path: "/advertisements/9", body: `{"price":1}`, wantStatus: http.StatusNotFound},
+		{name: "put id mismatch", method: http.MethodPut, path: "/advertisements/1", body: `{"id":5}`, wantStatus: http.StatusBadRequest},
+		{name: "put missing", method: http.MethodPut, path: "/advertisements/9", body: valid, wantStatus: http.StatusNotFound},
+		{name: "bad id", method: http.MethodDelete, path: "/advertisements/abc", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, step := range steps {
+		request := httptest.NewRequest(step.method, step.path, strings.NewReader(step.body))
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+
+		if recorder.Code != step.wantStatus {
+			t.Fatalf("%s: status = %d, want %d (%s)", step.name, recorder.Code, step.wantStatus, recorder.Body.String())
+		}
+	}
+
+	patched := indexer.documents[1]
+	if patched.Price != 12.5 || patched.Name != "Phone" {
+		t.Errorf("indexed document after patch = %+v, want price 12.5 and name kept", patched)
+	}
+
+	var fromStore model.Advertisement
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/advertisements/1", nil))
+	if err := json.NewDecoder(recorder.Body).Decode(&fromStore); err != nil || fromStore != patched {
+		t.Errorf("stored advertisement = %+v, want %+v (err %v)", fromStore, patched, err)
+	}
+
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodDelete, "/advertisements/1", nil))
+	if recorder.Code != http.StatusNoContent {
+		t.Fatalf("delete: status = %d, want %d", recorder.Code, http.StatusNoContent)
+	}
+	if _, ok := indexer.documents[1]; ok {
+		t.Errorf("document 1 is still indexed after delete")
+	}
+}
+
+func TestAdvertisementHandlerConcurrentPatches(t *testing.T) {
+	store := &memoryStore{advertisements: map[int]model.Advertisement{
+		1: {Index: 1, Name: "Phone", Brand: "Acme", Category: "Electronics", Price: 10, Currency: "USD", Stock: 3, Availability: "in_stock"},
+	}}
+	indexer := &memoryIndexer{documents: map[int]model.Advertisement{}}
+	handler := NewAdvertisementHandler(store, indexer)
+
+	router := chi.NewRouter()
+	router.Patch("/advertisements/{id}", handler.Patch)
+
+	// Каждый запрос меняет своё поле; ни одно изменение не должно затереть другое
+	bodies := []string{`{"price":12.5}`, `{"stock":7}`, `{"color":"black"}`, `{"size":"XL"}`}
+
+	var waitGroup sync.WaitGroup
+	for _, body := range bodies {
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPatch, "/advertisements/1", strings.NewReader(body)))
+			if recorder.Code != http.StatusOK {
+				t.Errorf("patch %s: status = %d, want %d", body, recorder.Code, http.StatusOK)
+			}
+		}()
+	}
+	waitGroup.Wait()
+
+	stored := store.advertisements[1]
+	if stored.Price != 12.5 || stored.Stock != 7 || stored.Color != "black" || stored.Size != "XL" || stored.Name != "Phone" {
+		t.Errorf("stored advertisement = %+v, want all patched fields kept", stored)
+	}
+}
+
+func TestAdvertisementHandlerReportsPendingIndex(t *testing.T) {
+	store := &memoryStore{advertisements: map[int]model.Advertisement{}}
+	indexer := &memoryIndexer{documents: map[int]model.Advertisement{}, err: errors.New("elasticsearch unavailable")}
+	handler := NewAdvertisementHandler(store, indexer)
+
+	router := chi.NewRouter()
+	router.Post("/advertisements", handler.Create)
+	router.Patch("/advertisements/{id}", handler.Patch)
+	router.Delete("/advertisements/{id}", handler.Delete)
+
+	steps := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		wantStatus int
+	}{
+		{name: "create", method: http.MethodPost, path: "/advertisements", wantStatus: http.StatusCreated,
+			body: `{"product_name":"Phone","brand":"Acme","category":"Electronics","price":10,"currency":"USD","stock":3,"availability":"in_stock"}`},
+		{name: "patch", method: http.MethodPatch, path: "/advertisements/1", body: `{"price":12.5}`, wantStatus: http.StatusOK},
+		{name: "delete", method: http.MethodDelete, path: "/advertisements/1", wantStatus: http.StatusNoContent},
+	}
+
+	// Изменение зафиксировано в БД и дойдёт до индекса через outbox, поэтому запрос успешен,
+	// но ответ сообщает, что в поиске его пока нет
+	for _, step := range steps {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(step.method, step.path, strings.NewReader(step.body)))
+
+		if recorder.Code != step.wantStatus {
+			t.Fatalf("%s: status = %d, want %d (%s)", step.name, recorder.Code, step.wantStatus, recorder.Body.String())
+		}
+		if got := recorder.Header().Get(IndexStatusHeader); got != IndexStatusPending {
+			t.Errorf("%s: %s = %q, want %q", step.name, IndexStatusHeader, got, IndexStatusPending)
+		}
+	}
+}
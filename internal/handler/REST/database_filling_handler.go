@@ -1,25 +1,35 @@
 package REST
 
 import (
-	"SearchService/internal/util"
+	"SearchService/internal/imports"
+	"SearchService/internal/model"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/chi/v5"
 	"io"
 	"net/http"
 	"os"
 	"strconv"
 )
 
+// DatabaseFillingHandler принимает CSV-файлы для импорта объявлений. Загрузка только ставит задание
+// в очередь, а сам импорт выполняется в фоне; его состояние доступно по GET /imports/{id}.
 type DatabaseFillingHandler struct {
-	*util.DatabaseFilling
+	runner *imports.Runner
+	// uploadDirectory — каталог, где загруженные файлы хранятся до завершения задания
+	uploadDirectory string
 }
 
-func NewDatabaseFillingHandler(filling *util.DatabaseFilling) *DatabaseFillingHandler {
-	return &DatabaseFillingHandler{DatabaseFilling: filling}
+func NewDatabaseFillingHandler(runner *imports.Runner, uploadDirectory string) *DatabaseFillingHandler {
+	return &DatabaseFillingHandler{runner: runner, uploadDirectory: uploadDirectory}
 }
 
+// FillDatabaseAsync — POST /imports: сохраняет файл, ставит задание импорта в очередь и отвечает 202 с его id
 func (handler *DatabaseFillingHandler) FillDatabaseAsync(writer http.ResponseWriter, request *http.Request) {
 	request.ParseMultipartForm(10 << 20)
 
-	file, _, err := request.FormFile("file")
+	file, header, err := request.FormFile("file")
 	if err != nil {
 		http.Error(writer, `{"error": "не удалось получить файл"}`, http.StatusBadRequest)
 		return
@@ -38,27 +48,92 @@ func (handler *DatabaseFillingHandler) FillDatabaseAsync(writer http.ResponseWri
 		return
 	}
 
-	// Создаём временный файл
-	tempFile, err := os.CreateTemp("", "uploaded-*.csv")
+	// Файл сохраняется в каталог загрузок: задание может начаться после перезапуска сервера
+	uploadedFile, err := os.CreateTemp(handler.uploadDirectory, "import-*.csv")
 	if err != nil {
-		http.Error(writer, `{"error": "не удалось создать временный файл"}`, http.StatusInternalServerError)
+		http.Error(writer, `{"error": "не удалось создать файл для импорта"}`, http.StatusInternalServerError)
 		return
 	}
-	defer os.Remove(tempFile.Name()) // удаляем временный файл после использования
-	defer tempFile.Close()
-
-	// Копируем содержимое в файл
-	if _, err := io.Copy(tempFile, file); err != nil {
+	_, err = io.Copy(uploadedFile, file)
+	if closeErr := uploadedFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(uploadedFile.Name())
 		http.Error(writer, `{"error": "ошибка копирования файла"}`, http.StatusInternalServerError)
 		return
 	}
 
-	if err := handler.FillDatabaseFromCSVAsync(tempFile.Name(), batchSize); err != nil {
-		http.Error(writer, `{"error": "ошибка загрузки данных в БД"}`, http.StatusInternalServerError)
+	job := model.ImportJob{FileName: header.Filename, FilePath: uploadedFile.Name(), BatchSize: batchSize}
+	if err := handler.runner.Submit(&job); err != nil {
+		os.Remove(uploadedFile.Name())
+		http.Error(writer, `{"error": "не удалось создать задание импорта"}`, http.StatusInternalServerError)
+		return
+	}
+
+	writer.Header().Set("Location", "/imports/"+strconv.FormatInt(job.ID, 10))
+	writeImportJob(writer, http.StatusAccepted, &job)
+}
+
+// GetImport — GET /imports/{id}: состояние и прогресс задания импорта
+func (handler *DatabaseFillingHandler) GetImport(writer http.ResponseWriter, request *http.Request) {
+	id, ok := parseImportJobID(writer, request)
+	if !ok {
+		return
+	}
+
+	job, err := handler.runner.Get(id)
+	if err != nil {
+		writeImportJobError(writer, err)
+		return
+	}
+
+	writeImportJob(writer, http.StatusOK, &job)
+}
+
+// CancelImport — POST /imports/{id}/cancel: отменяет задание из очереди или останавливает выполняемое
+func (handler *DatabaseFillingHandler) CancelImport(writer http.ResponseWriter, request *http.Request) {
+	id, ok := parseImportJobID(writer, request)
+	if !ok {
+		return
+	}
+
+	job, err := handler.runner.Cancel(id)
+	if err != nil {
+		writeImportJobError(writer, err)
 		return
 	}
 
-	writer.Header().Set("Content-Type", "multipart/form-data")
-	writer.WriteHeader(http.StatusOK)
-	writer.Write([]byte(`{"статус": "успешно"}`))
+	// Выполняемое задание останавливается асинхронно, итоговый статус появится в GET /imports/{id}
+	status := http.StatusOK
+	if job.Status == model.ImportRunning {
+		status = http.StatusAccepted
+	}
+	writeImportJob(writer, status, &job)
+}
+
+func parseImportJobID(writer http.ResponseWriter, request *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(request, "id"), 10, 64)
+	if err != nil || id <= 0 {
+		http.Error(writer, `{"error": "id задания должен быть положительным числом"}`, http.StatusBadRequest)
+		return 0, false
+	}
+	return id, true
+}
+
+func writeImportJobError(writer http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		http.Error(writer, `{"error": "задание импорта не найдено"}`, http.StatusNotFound)
+	case errors.Is(err, imports.ErrNotCancellable):
+		http.Error(writer, `{"error": "задание импорта уже завершено"}`, http.StatusConflict)
+	default:
+		http.Error(writer, `{"error": "ошибка работы с заданием импорта"}`, http.StatusInternalServerError)
+	}
+}
+
+func writeImportJob(writer http.ResponseWriter, status int, job *model.ImportJob) {
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(status)
+	json.NewEncoder(writer).Encode(job)
 }
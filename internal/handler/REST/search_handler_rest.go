@@ -4,8 +4,12 @@ import (
 	"SearchService/internal/model"
 	"SearchService/internal/ports"
 	"encoding/json"
+	"fmt"
+	"math"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 )
 
 type SearchHandler struct {
@@ -21,24 +25,56 @@ func (handler *SearchHandler) SearchInElastic(writer http.ResponseWriter, reques
 
 	query := request.URL.Query()
 
+	filters.Query = strings.TrimSpace(query.Get("q"))
 	filters.ProductName = query.Get("product_name")
 	filters.Brand = query.Get("brand")
 	filters.Category = query.Get("category")
 	filters.InStockOnly = query.Get("in_stock_only") == "true"
 
 	if minPrice := query.Get("min_price"); minPrice != "" {
-		if value, err := strconv.ParseFloat(minPrice, 64); err != nil {
+		if value, err := parsePrice(minPrice); err == nil {
 			filters.MinPrice = &value
 		}
 	}
 
 	if maxPrice := query.Get("max_price"); maxPrice != "" {
-		if val, err := strconv.ParseFloat(maxPrice, 64); err == nil {
+		if val, err := parsePrice(maxPrice); err == nil {
 			filters.MaxPrice = &val
 		}
 	}
 
-	results, err := handler.searcher.SearchAdvertisements(request.Context(), filters)
+	filters.WithFacets = query.Get("facets") == "true"
+	filters.FacetSelections = parseFacetSelections(query)
+
+	fuzziness, err := model.ParseFuzziness(query.Get("fuzziness"))
+	if err != nil {
+		http.Error(writer, "Некорректный параметр fuzziness: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	filters.Fuzziness = fuzziness
+	filters.WithDidYouMean = query.Get("did_you_mean") == "true"
+
+	priceAggregations, err := parsePriceAggregationOptions(query)
+	if err != nil {
+		http.Error(writer, "Некорректные параметры агрегаций по цене: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	filters.PriceAggregations = priceAggregations
+
+	sortOrder, err := model.ParseSortOrder(query.Get("sort"))
+	if err != nil {
+		http.Error(writer, "Некорректный параметр sort: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	filters.Sort = sortOrder
+
+	pagination, err := parsePagination(query)
+	if err != nil {
+		http.Error(writer, "Некорректные параметры пагинации: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	results, err := handler.searcher.SearchAdvertisements(request.Context(), filters, pagination)
 	if err != nil {
 		http.Error(writer, "Ошибка поиска: "+err.Error(), http.StatusInternalServerError)
 		return
@@ -47,3 +83,162 @@ func (handler *SearchHandler) SearchInElastic(writer http.ResponseWriter, reques
 	writer.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(writer).Encode(results)
 }
+
+// parseFacetSelections читает выбранные значения фасетов из параметров facet_<имя>.
+// Параметр можно повторять: facet_brand=Braun&facet_brand=Herman Ltd.
+func parseFacetSelections(query url.Values) map[model.Facet][]string {
+	selections := make(map[model.Facet][]string)
+	for _, facet := range model.Facets {
+		for _, value := range query["facet_"+string(facet)] {
+			if value != "" {
+				selections[facet] = append(selections[facet], value)
+			}
+		}
+	}
+	return selections
+}
+
+// parsePriceAggregationOptions читает запрос агрегаций по цене:
+// price_stats=true — min/max/avg, price_interval=100 — гистограмма с шагом (на широком разбросе цен
+// шаг увеличивается до model.MaxPriceHistogramBuckets корзин, фактический возвращается в ответе),
+// price_ranges=0-100,100-500,500- — пользовательские диапазоны (границы можно опускать).
+func parsePriceAggregationOptions(query url.Values) (model.PriceAggregationOptions, error) {
+	options := model.PriceAggregationOptions{
+		WithStats: query.Get("price_stats") == "true",
+	}
+
+	if interval := query.Get("price_interval"); interval != "" {
+		value, err := parsePrice(interval)
+		if err != nil || value < model.MinPriceHistogramInterval {
+			return model.PriceAggregationOptions{}, fmt.Errorf("price_interval должен быть числом >= %g", model.MinPriceHistogramInterval)
+		}
+		options.HistogramInterval = value
+	}
+
+	if ranges := query.Get("price_ranges"); ranges != "" {
+		if options.HistogramInterval > 0 {
+			return model.PriceAggregationOptions{}, fmt.Errorf("нельзя одновременно передавать price_interval и price_ranges")
+		}
+		for _, rawRange := range strings.Split(ranges, ",") {
+			priceRange, err := parsePriceRange(rawRange)
+			if err != nil {
+				return model.PriceAggregationOptions{}, err
+			}
+			options.Ranges = append(options.Ranges, priceRange)
+		}
+	}
+
+	return options, nil
+}
+
+// parsePriceRange разбирает диапазон вида "from-to", где любая из границ может отсутствовать
+func parsePriceRange(raw string) (model.PriceRange, error) {
+	fromStr, toStr, found := strings.Cut(strings.TrimSpace(raw), "-")
+	if !found || (fromStr == "" && toStr == "") {
+		return model.PriceRange{}, fmt.Errorf("неверный диапазон цен: %q", raw)
+	}
+
+	var priceRange model.PriceRange
+	if fromStr != "" {
+		from, err := parsePrice(fromStr)
+		if err != nil {
+			return model.PriceRange{}, fmt.Errorf("неверная нижняя граница диапазона %q", raw)
+		}
+		priceRange.From = &from
+	}
+	if toStr != "" {
+		to, err := parsePrice(toStr)
+		if err != nil {
+			return model.PriceRange{}, fmt.Errorf("неверная верхняя граница диапазона %q", raw)
+		}
+		priceRange.To = &to
+	}
+	if priceRange.From != nil && priceRange.To != nil && *priceRange.From >= *priceRange.To {
+		return model.PriceRange{}, fmt.Errorf("нижняя граница диапазона %q должна быть меньше верхней", raw)
+	}
+
+	return priceRange, nil
+}
+
+// parsePrice разбирает цену, отклоняя NaN и бесконечности: ParseFloat их принимает,
+// а в запросе к Elasticsearch они не сериализуются в JSON
+func parsePrice(raw string) (float64, error) {
+	value, err := strconv.ParseFloat(strings.TrimSpace(raw), 64)
+	if err != nil {
+		return 0, err
+	}
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return 0, fmt.Errorf("цена должна быть конечным числом: %s", raw)
+	}
+	return value, nil
+}
+
+// parsePagination читает окно выдачи из page/page_size либо из from/size.
+// Если параметры не переданы, возвращается первая страница размером model.DefaultPageSize.
+func parsePagination(query url.Values) (model.Pagination, error) {
+	pagination := model.Pagination{Size: model.DefaultPageSize}
+
+	_, hasPage := query["page"]
+	_, hasFrom := query["from"]
+	if hasPage && hasFrom {
+		return model.Pagination{}, fmt.Errorf("нельзя одновременно передавать page и from")
+	}
+
+	size, err := parsePositiveInt(query, "page_size", "size")
+	if err != nil {
+		return model.Pagination{}, err
+	}
+	if size > 0 {
+		pagination.Size = size
+	}
+	if pagination.Size > model.MaxPageSize {
+		return model.Pagination{}, fmt.Errorf("размер страницы не может превышать %d", model.MaxPageSize)
+	}
+
+	windowErr := fmt.Errorf("окно выдачи не может выходить за первые %d результатов", model.MaxResultWindow)
+
+	// Границы проверяются до умножения и сложения, чтобы огромные page и from не переполняли int
+	if hasPage {
+		page, err := strconv.Atoi(query.Get("page"))
+		if err != nil || page < 1 {
+			return model.Pagination{}, fmt.Errorf("page должен быть числом >= 1")
+		}
+		if page > model.MaxResultWindow/pagination.Size {
+			return model.Pagination{}, windowErr
+		}
+		pagination.From = (page - 1) * pagination.Size
+	}
+
+	if hasFrom {
+		from, err := strconv.Atoi(query.Get("from"))
+		if err != nil || from < 0 {
+			return model.Pagination{}, fmt.Errorf("from должен быть числом >= 0")
+		}
+		if from > model.MaxResultWindow {
+			return model.Pagination{}, windowErr
+		}
+		pagination.From = from
+	}
+
+	if pagination.From+pagination.Size > model.MaxResultWindow {
+		return model.Pagination{}, windowErr
+	}
+
+	return pagination, nil
+}
+
+// parsePositiveInt возвращает значение первого переданного параметра из names или 0, если ни один не передан
+func parsePositiveInt(query url.Values, names ...string) (int, error) {
+	for _, name := range names {
+		raw := query.Get(name)
+		if raw == "" {
+			continue
+		}
+		value, err := strconv.Atoi(raw)
+		if err != nil || value <= 0 {
+			return 0, fmt.Errorf("%s должен быть числом > 0", name)
+		}
+		return value, nil
+	}
+	return 0, nil
+}
@@ -0,0 +1,156 @@
+package REST
+
+import (
+	"SearchService/internal/model"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestParsePagination(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    model.Pagination
+		wantErr bool
+	}{
+		{
+			name:  "defaults",
+			input: "",
+			want:  model.Pagination{From: 0, Size: model.DefaultPageSize},
+		},
+		{
+			name:  "page and page_size",
+			input: "page=3&page_size=10",
+			want:  model.Pagination{From: 20, Size: 10},
+		},
+		{
+			name:  "from and size",
+			input: "from=15&size=5",
+			want:  model.Pagination{From: 15, Size: 5},
+		},
+		{
+			name:    "page and from together",
+			input:   "page=2&from=10",
+			wantErr: true,
+		},
+		{
+			name:    "zero page",
+			input:   "page=0",
+			wantErr: true,
+		},
+		{
+			name:    "page_size too large",
+			input:   "page_size=1000",
+			wantErr: true,
+		},
+		{
+			name:    "outside result window",
+			input:   "page=1000&page_size=100",
+			wantErr: true,
+		},
+		{
+			name:  "last page inside result window",
+			input: "page=100&page_size=100",
+			want:  model.Pagination{From: 9900, Size: 100},
+		},
+		{
+			name:    "page overflows int",
+			input:   "page=9223372036854775807&page_size=20",
+			wantErr: true,
+		},
+		{
+			name:    "from overflows int",
+			input:   "from=9223372036854775807&size=20",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, _ := url.ParseQuery(tt.input)
+			got, err := parsePagination(query)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parsePagination() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("parsePagination() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePriceRange(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		wantFrom *float64
+		wantTo   *float64
+		wantErr  bool
+	}{
+		{name: "closed range", input: "100-500", wantFrom: ptr(100), wantTo: ptr(500)},
+		{name: "open upper bound", input: "500-", wantFrom: ptr(500)},
+		{name: "open lower bound", input: "-100", wantTo: ptr(100)},
+		{name: "no bounds", input: "-", wantErr: true},
+		{name: "no separator", input: "100", wantErr: true},
+		{name: "inverted bounds", input: "500-100", wantErr: true},
+		{name: "NaN bound", input: "NaN-100", wantErr: true},
+		{name: "infinite bound", input: "100-Inf", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parsePriceRange(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parsePriceRange() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !equalBound(got.From, tt.wantFrom) || !equalBound(got.To, tt.wantTo) {
+				t.Errorf("parsePriceRange() = %+v, want from %v to %v", got, tt.wantFrom, tt.wantTo)
+			}
+		})
+	}
+}
+
+func TestParsePriceAggregationOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    model.PriceAggregationOptions
+		wantErr bool
+	}{
+		{name: "stats and histogram", input: "price_stats=true&price_interval=50", want: model.PriceAggregationOptions{WithStats: true, HistogramInterval: 50}},
+		{name: "minimum interval", input: "price_interval=1", want: model.PriceAggregationOptions{HistogramInterval: 1}},
+		{name: "interval below minimum", input: "price_interval=0.0001", wantErr: true},
+		{name: "NaN interval", input: "price_interval=NaN", wantErr: true},
+		{name: "infinite interval", input: "price_interval=%2BInf", wantErr: true},
+		{name: "interval with ranges", input: "price_interval=10&price_ranges=0-100", wantErr: true},
+		{name: "invalid range", input: "price_ranges=0-100,abc", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, _ := url.ParseQuery(tt.input)
+			got, err := parsePriceAggregationOptions(query)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parsePriceAggregationOptions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parsePriceAggregationOptions() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func ptr(value float64) *float64 {
+	return &value
+}
+
+func equalBound(got, want *float64) bool {
+	if got == nil || want == nil {
+		return got == want
+	}
+	return *got == *want
+}
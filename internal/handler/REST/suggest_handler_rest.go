@@ -0,0 +1,62 @@
+package REST
+
+import (
+	"SearchService/internal/model"
+	"SearchService/internal/ports"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+type SuggestHandler struct {
+	suggester ports.AdvertisementSuggester
+}
+
+func NewSuggestHandler(suggester ports.AdvertisementSuggester) *SuggestHandler {
+	return &SuggestHandler{suggester: suggester}
+}
+
+// Suggest возвращает дополнения названий и брендов для префикса.
+// Параметры: prefix (обязателен), category — ограничение по категории, limit — количество подсказок.
+func (handler *SuggestHandler) Suggest(writer http.ResponseWriter, request *http.Request) {
+	suggestRequest, err := parseSuggestRequest(request.URL.Query())
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	results, err := handler.suggester.SuggestAdvertisements(request.Context(), suggestRequest)
+	if err != nil {
+		http.Error(writer, "Ошибка автодополнения: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(writer).Encode(results)
+}
+
+// parseSuggestRequest читает и проверяет параметры автодополнения
+func parseSuggestRequest(query url.Values) (model.SuggestRequest, error) {
+	suggestRequest := model.SuggestRequest{
+		Prefix:   strings.TrimSpace(query.Get("prefix")),
+		Category: query.Get("category"),
+		Limit:    model.DefaultSuggestLimit,
+	}
+
+	if suggestRequest.Prefix == "" {
+		return model.SuggestRequest{}, fmt.Errorf("Параметр prefix обязателен")
+	}
+
+	if limit := query.Get("limit"); limit != "" {
+		value, err := strconv.Atoi(limit)
+		if err != nil || value <= 0 || value > model.MaxSuggestLimit {
+			return model.SuggestRequest{}, fmt.Errorf("limit должен быть числом от 1 до %d", model.MaxSuggestLimit)
+		}
+		suggestRequest.Limit = value
+	}
+
+	return suggestRequest, nil
+}
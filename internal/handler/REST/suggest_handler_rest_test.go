@@ -0,0 +1,70 @@
+package REST
+
+import (
+	"SearchService/internal/model"
+	"net/url"
+	"testing"
+)
+
+func TestParseSuggestRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    model.SuggestRequest
+		wantErr bool
+	}{
+		{
+			name:  "defaults",
+			input: "prefix=sam",
+			want:  model.SuggestRequest{Prefix: "sam", Limit: model.DefaultSuggestLimit},
+		},
+		{
+			name:  "category and limit",
+			input: "prefix=%20sam%20&category=Phones&limit=5",
+			want:  model.SuggestRequest{Prefix: "sam", Category: "Phones", Limit: 5},
+		},
+		{
+			name:  "maximum limit",
+			input: "prefix=sam&limit=50",
+			want:  model.SuggestRequest{Prefix: "sam", Limit: model.MaxSuggestLimit},
+		},
+		{
+			name:    "missing prefix",
+			input:   "category=Phones",
+			wantErr: true,
+		},
+		{
+			name:    "blank prefix",
+			input:   "prefix=%20%20",
+			wantErr: true,
+		},
+		{
+			name:    "zero limit",
+			input:   "prefix=sam&limit=0",
+			wantErr: true,
+		},
+		{
+			name:    "limit too large",
+			input:   "prefix=sam&limit=51",
+			wantErr: true,
+		},
+		{
+			name:    "limit not a number",
+			input:   "prefix=sam&limit=ten",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, _ := url.ParseQuery(tt.input)
+			got, err := parseSuggestRequest(query)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseSuggestRequest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("parseSuggestRequest() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,249 @@
+package imports
+
+// runner выполняет задания импорта CSV в фоне. Задания и их прогресс хранятся в таблице import_jobs,
+// поэтому состояние доступно и после перезапуска: задания из очереди выполняются после старта,
+// а задание, прерванное остановкой сервера, завершается с ошибкой — часть его строк уже вставлена,
+// и повторный запуск продублировал бы их.
+//
+// Импорт поддерживает только один экземпляр сервиса на базу: при старте Run считает прерванными все
+// задания в running, а загруженные файлы лежат в локальном IMPORT_DIRECTORY и не видны другим машинам.
+// Второй экземпляр с той же БД завершил бы с ошибкой задания первого и не нашёл бы их файлы.
+
+import (
+	"SearchService/internal/model"
+	"SearchService/internal/ports"
+	"SearchService/internal/util"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// ImportFunc загружает CSV-файл в БД, сообщая о каждом пакете через onBatch (см. util.DatabaseFilling.FillDatabaseFromCSV)
+type ImportFunc func(ctx context.Context, filepath string, batchSize int, onBatch func(util.BatchResult)) (util.FillResult, error)
+
+// maxErrorSamples — сколько первых ошибок сохраняется в задании
+const maxErrorSamples = 10
+
+// interruptedMessage — ошибка задания, выполнение которого прервала остановка сервера
+const interruptedMessage = "импорт прерван остановкой сервера"
+
+// ErrNotCancellable возвращается при отмене задания, которое уже завершено
+var ErrNotCancellable = errors.New("задание импорта нельзя отменить")
+
+// Options — настройки Runner
+type Options struct {
+	// PollInterval — как часто проверять очередь, если о новых заданиях не сообщалось
+	PollInterval time.Duration
+}
+
+// DefaultOptions используются для полей Options, оставленных нулевыми
+var DefaultOptions = Options{
+	PollInterval: 5 * time.Second,
+}
+
+type Runner struct {
+	store      ports.ImportJobStore
+	importFile ImportFunc
+	options    Options
+	// wake сообщает циклу Run о новом задании, не дожидаясь PollInterval
+	wake chan struct{}
+
+	mu sync.Mutex
+	// cancels — функции отмены выполняемых заданий
+	cancels map[int64]context.CancelFunc
+}
+
+func NewRunner(store ports.ImportJobStore, importFile ImportFunc, options Options) *Runner {
+	if options.PollInterval <= 0 {
+		options.PollInterval = DefaultOptions.PollInterval
+	}
+
+	return &Runner{
+		store:      store,
+		importFile: importFile,
+		options:    options,
+		wake:       make(chan struct{}, 1),
+		cancels:    make(map[int64]context.CancelFunc),
+	}
+}
+
+// Submit ставит задание в очередь. После вызова файл job.FilePath принадлежит Runner и удаляется по завершении задания.
+func (r *Runner) Submit(job *model.ImportJob) error {
+	if err := r.store.CreateImportJob(job); err != nil {
+		return err
+	}
+
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+func (r *Runner) Get(id int64) (model.ImportJob, error) {
+	return r.store.GetImportJob(id)
+}
+
+// Cancel отменяет задание: задание из очереди отменяется сразу, и его файл удаляется, а выполняемое
+// останавливается после текущих пакетов и получает статус cancelled. Возвращает состояние задания на момент вызова.
+func (r *Runner) Cancel(id int64) (model.ImportJob, error) {
+	cancelled, err := r.store.CancelQueuedImportJob(id)
+	if err != nil {
+		return model.ImportJob{}, err
+	}
+
+	if cancelled {
+		job, err := r.store.GetImportJob(id)
+		if err != nil {
+			return model.ImportJob{}, err
+		}
+		removeJobFile(job)
+		return job, nil
+	}
+
+	// claim захватывает задание и регистрирует его отмену под r.mu, поэтому захваченное задание уже в cancels
+	r.mu.Lock()
+	cancel, running := r.cancels[id]
+	r.mu.Unlock()
+
+	if !running {
+		job, err := r.store.GetImportJob(id)
+		if err != nil {
+			return model.ImportJob{}, err
+		}
+		return job, fmt.Errorf("%w: задание %d в состоянии %s", ErrNotCancellable, id, job.Status)
+	}
+	cancel()
+
+	return r.store.GetImportJob(id)
+}
+
+// Run выполняет задания из очереди по одному до отмены ctx. Перед этим задания, оставшиеся в running
+// после прошлой остановки сервера, завершаются с ошибкой. Отмена ctx прерывает текущее задание.
+func (r *Runner) Run(ctx context.Context) {
+	interrupted, err := r.store.FailInterruptedImportJobs(interruptedMessage)
+	if err != nil {
+		log.Printf("ошибка завершения прерванных заданий импорта: %v", err)
+	} else if interrupted > 0 {
+		log.Printf("Прерванных заданий импорта завершено с ошибкой: %d", interrupted)
+	}
+
+	for ctx.Err() == nil {
+		job, jobCtx, found, err := r.claim(ctx)
+		if err != nil {
+			log.Printf("ошибка получения задания импорта: %v", err)
+		}
+		if found {
+			r.execute(ctx, jobCtx, job)
+			continue
+		}
+
+		select {
+		case <-r.wake:
+		case <-time.After(r.options.PollInterval):
+		case <-ctx.Done():
+		}
+	}
+}
+
+// claim берёт следующее задание из очереди и регистрирует функцию его отмены. Оба шага выполняются
+// под r.mu: иначе Cancel между ними увидел бы задание уже не в очереди, но ещё не в cancels.
+func (r *Runner) claim(ctx context.Context) (job model.ImportJob, jobCtx context.Context, found bool, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	job, found, err = r.store.ClaimNextImportJob()
+	if err != nil || !found {
+		return job, nil, found, err
+	}
+
+	jobCtx, cancel := context.WithCancel(ctx)
+	r.cancels[job.ID] = cancel
+	return job, jobCtx, true, nil
+}
+
+// execute выполняет задание, захваченное claim, сохраняя прогресс после каждого пакета, и фиксирует итоговый статус
+func (r *Runner) execute(ctx context.Context, jobCtx context.Context, job model.ImportJob) {
+	defer func() {
+		r.mu.Lock()
+		cancel := r.cancels[job.ID]
+		delete(r.cancels, job.ID)
+		r.mu.Unlock()
+		cancel()
+	}()
+
+	log.Printf("Запущено задание импорта %d (%s)", job.ID, job.FileName)
+	progress := &jobProgress{store: r.store, job: job}
+	result, err := r.importFile(jobCtx, job.FilePath, job.BatchSize, progress.add)
+
+	status, message := finalStatus(ctx, jobCtx, result, err)
+	if err := r.store.FinishImportJob(job.ID, status, message); err != nil {
+		log.Printf("ошибка сохранения итога задания импорта %d: %v", job.ID, err)
+	}
+	removeJobFile(job)
+	log.Printf("Задание импорта %d завершено: %s, сохранено %d строк, не удалось сохранить %d",
+		job.ID, status, progress.job.RowsProcessed, progress.job.RowsFailed)
+}
+
+// removeJobFile удаляет загруженный файл завершённого или отменённого задания
+func removeJobFile(job model.ImportJob) {
+	if err := os.Remove(job.FilePath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Printf("ошибка удаления файла задания импорта %d: %v", job.ID, err)
+	}
+}
+
+// jobProgress накапливает результаты пакетов, которые воркеры импорта сообщают конкурентно
+type jobProgress struct {
+	store ports.ImportJobStore
+
+	mu  sync.Mutex
+	job model.ImportJob
+}
+
+func (progress *jobProgress) add(result util.BatchResult) {
+	progress.mu.Lock()
+	defer progress.mu.Unlock()
+
+	if result.Err != nil {
+		progress.job.RowsFailed += result.Rows
+		progress.addSample(result.Err)
+	} else {
+		progress.job.RowsProcessed += result.Rows
+	}
+
+	job := progress.job
+	if err := progress.store.SaveImportProgress(job.ID, job.RowsProcessed, job.RowsFailed, job.ErrorSamples); err != nil {
+		log.Printf("ошибка сохранения прогресса: %v", err)
+	}
+}
+
+func (progress *jobProgress) addSample(err error) {
+	if len(progress.job.ErrorSamples) < maxErrorSamples {
+		progress.job.ErrorSamples = append(progress.job.ErrorSamples, err.Error())
+	}
+}
+
+// finalStatus определяет итоговый статус задания по результату импорта и причине отмены контекста
+func finalStatus(ctx context.Context, jobCtx context.Context, result util.FillResult, err error) (model.ImportJobStatus, string) {
+	switch {
+	case ctx.Err() != nil:
+		return model.ImportFailed, interruptedMessage
+	case jobCtx.Err() != nil:
+		return model.ImportCancelled, ""
+	case err != nil:
+		return model.ImportFailed, err.Error()
+	}
+
+	switch result.Status() {
+	case util.FillSucceeded:
+		return model.ImportSucceeded, ""
+	case util.FillPartial:
+		return model.ImportPartial, fmt.Sprintf("не удалось сохранить %d строк в %d пакетах", result.RowsFailed, len(result.FailedBatches))
+	default:
+		return model.ImportFailed, fmt.Sprintf("не удалось сохранить ни одной строки, неудачных пакетов: %d", len(result.FailedBatches))
+	}
+}
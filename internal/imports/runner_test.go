@@ -0,0 +1,301 @@
+package imports
+
+import (
+	"SearchService/internal/model"
+	"SearchService/internal/util"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryJobs — ImportJobStore в памяти
+type memoryJobs struct {
+	mu   sync.Mutex
+	jobs []model.ImportJob
+}
+
+func (store *memoryJobs) find(id int64) *model.ImportJob {
+	for i := range store.jobs {
+		if store.jobs[i].ID == id {
+			return &store.jobs[i]
+		}
+	}
+	return nil
+}
+
+func (store *memoryJobs) CreateImportJob(job *model.ImportJob) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	job.ID = int64(len(store.jobs) + 1)
+	job.Status = model.ImportQueued
+	job.CreatedAt = time.Now()
+	store.jobs = append(store.jobs, *job)
+	return nil
+}
+
+func (store *memoryJobs) GetImportJob(id int64) (model.ImportJob, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if job := store.find(id); job != nil {
+		return *job, nil
+	}
+	return model.ImportJob{}, fmt.Errorf("задание %d: %w", id, sql.ErrNoRows)
+}
+
+func (store *memoryJobs) ClaimNextImportJob() (model.ImportJob, bool, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	for i := range store.jobs {
+		if store.jobs[i].Status == model.ImportQueued {
+			store.jobs[i].Status = model.ImportRunning
+			return store.jobs[i], true, nil
+		}
+	}
+	return model.ImportJob{}, false, nil
+}
+
+func (store *memoryJobs) SaveImportProgress(id int64, rowsProcessed int, rowsFailed int, errorSamples []string) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	job := store.find(id)
+	job.RowsProcessed, job.RowsFailed, job.ErrorSamples = rowsProcessed, rowsFailed, errorSamples
+	return nil
+}
+
+func (store *memoryJobs) FinishImportJob(id int64, status model.ImportJobStatus, message string) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	job := store.find(id)
+	job.Status, job.Error = status, message
+	return nil
+}
+
+func (store *memoryJobs) CancelQueuedImportJob(id int64) (bool, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	job := store.find(id)
+	if job == nil || job.Status != model.ImportQueued {
+		return false, nil
+	}
+	job.Status = model.ImportCancelled
+	return true, nil
+}
+
+func (store *memoryJobs) FailInterruptedImportJobs(message string) (int, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	failed := 0
+	for i := range store.jobs {
+		if store.jobs[i].Status == model.ImportRunning {
+			store.jobs[i].Status, store.jobs[i].Error = model.ImportFailed, message
+			failed++
+		}
+	}
+	return failed, nil
+}
+
+// waitForStatus ждёт, пока задание перейдёт в status
+func waitForStatus(t *testing.T, store *memoryJobs, id int64, status model.ImportJobStatus) model.ImportJob {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		job, _ := store.GetImportJob(id)
+		if job.Status == status {
+			return job
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job %d status = %s, want %s", id, job.Status, status)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func uploadedFile(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "import.csv")
+	if err := os.WriteFile(path, []byte("id\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestRunnerFinishedJobs(t *testing.T) {
+	saveErr := errors.New("duplicate key")
+	tests := []struct {
+		name        string
+		batches     []util.BatchResult
+		importErr   error
+		wantStatus  model.ImportJobStatus
+		wantRows    int
+		wantFailed  int
+		wantSamples int
+	}{
+		{
+			name:       "all batches saved",
+			batches:    []util.BatchResult{{Rows: 100}, {Rows: 40}},
+			wantStatus: model.ImportSucceeded,
+			wantRows:   140,
+		},
+		{
+			name:        "failed batch",
+			batches:     []util.BatchResult{{Rows: 100}, {Rows: 100, Err: saveErr}},
+			wantStatus:  model.ImportPartial,
+			wantRows:    100,
+			wantFailed:  100,
+			wantSamples: 1,
+		},
+		{
+			name:        "all batches failed",
+			batches:     []util.BatchResult{{Rows: 100, Err: saveErr}, {Rows: 100, Err: saveErr}},
+			wantStatus:  model.ImportFailed,
+			wantFailed:  200,
+			wantSamples: 2,
+		},
+		{
+			name:       "import error",
+			batches:    []util.BatchResult{{Rows: 100}},
+			importErr:  errors.New("ошибка парсинга данных"),
+			wantStatus: model.ImportFailed,
+			wantRows:   100,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			importFile := func(ctx context.Context, filepath string, batchSize int, onBatch func(util.BatchResult)) (util.FillResult, error) {
+				result := util.FillResult{Err: tt.importErr}
+				for _, batch := range tt.batches {
+					onBatch(batch)
+					if batch.Err != nil {
+						result.RowsFailed += batch.Rows
+						result.FailedBatches = append(result.FailedBatches, util.FailedBatch{Err: batch.Err})
+					} else {
+						result.RowsSaved += batch.Rows
+					}
+				}
+				return result, tt.importErr
+			}
+			store := &memoryJobs{}
+			runner := NewRunner(store, importFile, Options{PollInterval: time.Millisecond})
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			go runner.Run(ctx)
+
+			job := model.ImportJob{FileName: "import.csv", FilePath: uploadedFile(t), BatchSize: 100}
+			if err := runner.Submit(&job); err != nil {
+				t.Fatal(err)
+			}
+
+			got := waitForStatus(t, store, job.ID, tt.wantStatus)
+			if got.RowsProcessed != tt.wantRows || got.RowsFailed != tt.wantFailed || len(got.ErrorSamples) != tt.wantSamples {
+				t.Errorf("job = %+v, want %d rows, %d failed, %d samples", got, tt.wantRows, tt.wantFailed, tt.wantSamples)
+			}
+			if (tt.wantStatus != model.ImportSucceeded) != (got.Error != "") {
+				t.Errorf("job error = %q for status %s", got.Error, got.Status)
+			}
+			if _, err := runner.Cancel(job.ID); !errors.Is(err, ErrNotCancellable) {
+				t.Errorf("Cancel() of finished job error = %v, want ErrNotCancellable", err)
+			}
+		})
+	}
+}
+
+func TestRunnerCancel(t *testing.T) {
+	started := make(chan struct{})
+	importFile := func(ctx context.Context, filepath string, batchSize int, onBatch func(util.BatchResult)) (util.FillResult, error) {
+		close(started)
+		onBatch(util.BatchResult{Rows: 10})
+		<-ctx.Done()
+		return util.FillResult{RowsSaved: 10, Err: ctx.Err()}, ctx.Err()
+	}
+	store := &memoryJobs{}
+	runner := NewRunner(store, importFile, Options{PollInterval: time.Millisecond})
+
+	running := model.ImportJob{FilePath: uploadedFile(t), BatchSize: 10}
+	queued := model.ImportJob{FilePath: uploadedFile(t), BatchSize: 10}
+	runner.Submit(&running)
+	runner.Submit(&queued)
+
+	// Задание из очереди отменяется сразу, и его файл больше не нужен
+	if job, err := runner.Cancel(queued.ID); err != nil || job.Status != model.ImportCancelled {
+		t.Fatalf("Cancel() of queued job = %s, %v; want cancelled", job.Status, err)
+	}
+	if _, err := os.Stat(queued.FilePath); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("file of cancelled queued job was not removed: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go runner.Run(ctx)
+	<-started
+
+	if _, err := runner.Cancel(running.ID); err != nil {
+		t.Fatalf("Cancel() of running job error = %v", err)
+	}
+	got := waitForStatus(t, store, running.ID, model.ImportCancelled)
+	if got.RowsProcessed != 10 {
+		t.Errorf("cancelled job processed %d rows, want 10", got.RowsProcessed)
+	}
+	if _, err := os.Stat(running.FilePath); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("file of finished job was not removed: %v", err)
+	}
+}
+
+// slowClaimJobs сообщает в claimed о захвате задания и задерживает возврат из ClaimNextImportJob,
+// открывая промежуток между захватом задания в хранилище и его регистрацией в Runner
+type slowClaimJobs struct {
+	*memoryJobs
+	claimed chan int64
+}
+
+func (store *slowClaimJobs) ClaimNextImportJob() (model.ImportJob, bool, error) {
+	job, found, err := store.memoryJobs.ClaimNextImportJob()
+	if found {
+		store.claimed <- job.ID
+		time.Sleep(50 * time.Millisecond)
+	}
+	return job, found, err
+}
+
+func TestRunnerCancelRightAfterClaim(t *testing.T) {
+	importFile := func(ctx context.Context, filepath string, batchSize int, onBatch func(util.BatchResult)) (util.FillResult, error) {
+		<-ctx.Done()
+		return util.FillResult{Err: ctx.Err()}, ctx.Err()
+	}
+	store := &slowClaimJobs{memoryJobs: &memoryJobs{}, claimed: make(chan int64, 1)}
+	runner := NewRunner(store, importFile, Options{PollInterval: time.Millisecond})
+
+	job := model.ImportJob{FilePath: uploadedFile(t), BatchSize: 10}
+	runner.Submit(&job)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go runner.Run(ctx)
+	<-store.claimed
+
+	// Задание уже running в хранилище, но Runner ещё не вернулся из захвата
+	if _, err := runner.Cancel(job.ID); err != nil {
+		t.Fatalf("Cancel() right after claim error = %v", err)
+	}
+	waitForStatus(t, store.memoryJobs, job.ID, model.ImportCancelled)
+}
+
+func TestRunnerFailsInterruptedJobs(t *testing.T) {
+	store := &memoryJobs{jobs: []model.ImportJob{{ID: 1, Status: model.ImportRunning}}}
+	runner := NewRunner(store, nil, Options{PollInterval: time.Millisecond})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go runner.Run(ctx)
+
+	if got := waitForStatus(t, store, 1, model.ImportFailed); got.Error != interruptedMessage {
+		t.Errorf("interrupted job error = %q, want %q", got.Error, interruptedMessage)
+	}
+}
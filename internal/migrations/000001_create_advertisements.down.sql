@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS advertisements;
+DROP FUNCTION IF EXISTS advertisements_set_updated_at();
@@ -0,0 +1,36 @@
+-- Таблица объявлений. IF NOT EXISTS позволяет принять под управление миграций базы,
+-- в которых таблица была создана вручную до появления миграций.
+CREATE TABLE IF NOT EXISTS advertisements (
+    id           BIGSERIAL PRIMARY KEY,
+    product_name TEXT           NOT NULL,
+    description  TEXT           NOT NULL DEFAULT '',
+    brand        TEXT           NOT NULL DEFAULT '',
+    category     TEXT           NOT NULL DEFAULT '',
+    price        NUMERIC(12, 2) NOT NULL DEFAULT 0,
+    currency     TEXT           NOT NULL DEFAULT '',
+    stock        INTEGER        NOT NULL DEFAULT 0,
+    ean          TEXT           NOT NULL DEFAULT '',
+    color        TEXT           NOT NULL DEFAULT '',
+    size         TEXT           NOT NULL DEFAULT '',
+    availability TEXT           NOT NULL DEFAULT ''
+);
+
+ALTER TABLE advertisements ADD COLUMN IF NOT EXISTS created_at TIMESTAMPTZ NOT NULL DEFAULT now();
+ALTER TABLE advertisements ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ NOT NULL DEFAULT now();
+
+CREATE INDEX IF NOT EXISTS advertisements_ean_idx ON advertisements (ean);
+CREATE INDEX IF NOT EXISTS advertisements_brand_idx ON advertisements (brand);
+CREATE INDEX IF NOT EXISTS advertisements_category_idx ON advertisements (category);
+
+-- updated_at обновляется при любом изменении строки, даже если запрос его не передаёт
+CREATE OR REPLACE FUNCTION advertisements_set_updated_at() RETURNS TRIGGER AS $$
+BEGIN
+    NEW.updated_at = now();
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS advertisements_set_updated_at ON advertisements;
+CREATE TRIGGER advertisements_set_updated_at
+    BEFORE UPDATE ON advertisements
+    FOR EACH ROW EXECUTE FUNCTION advertisements_set_updated_at();
@@ -0,0 +1,11 @@
+-- Транзакционный outbox событий об изменении объявлений (см. internal/repository/outbox_repository.go)
+CREATE TABLE IF NOT EXISTS advertisement_outbox (
+    id               BIGSERIAL PRIMARY KEY,
+    advertisement_id BIGINT      NOT NULL,
+    event_type       TEXT        NOT NULL,
+    payload          JSONB       NOT NULL,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT now(),
+    sent_at          TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS advertisement_outbox_unsent_idx ON advertisement_outbox (id) WHERE sent_at IS NULL;
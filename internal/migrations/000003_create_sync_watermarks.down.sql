@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS advertisements_updated_at_id_idx;
+DROP TABLE IF EXISTS sync_watermarks;
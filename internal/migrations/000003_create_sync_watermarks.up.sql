@@ -0,0 +1,11 @@
+-- Отметки инкрементальной синхронизации с Elasticsearch: до какой пары (updated_at, id)
+-- изменения уже проиндексированы
+CREATE TABLE IF NOT EXISTS sync_watermarks (
+    name       TEXT PRIMARY KEY,
+    updated_at TIMESTAMPTZ NOT NULL,
+    last_id    BIGINT      NOT NULL,
+    synced_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Выборка изменённых строк идёт по ключу (updated_at, id)
+CREATE INDEX IF NOT EXISTS advertisements_updated_at_id_idx ON advertisements (updated_at, id);
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS advertisements_deleted_id_idx;
+
+-- Без колонки deleted_at мягко удалённые строки снова стали бы видимыми, поэтому они удаляются окончательно
+DELETE FROM advertisements WHERE deleted_at IS NOT NULL;
+
+ALTER TABLE advertisements DROP COLUMN IF EXISTS deleted_at;
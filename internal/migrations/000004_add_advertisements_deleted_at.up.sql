@@ -0,0 +1,5 @@
+-- Мягкое удаление: строка остаётся в таблице с отметкой deleted_at, чтобы синхронизация
+-- с Elasticsearch могла удалить документ из индекса
+ALTER TABLE advertisements ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS advertisements_deleted_id_idx ON advertisements (id) WHERE deleted_at IS NOT NULL;
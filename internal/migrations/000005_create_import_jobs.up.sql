@@ -0,0 +1,17 @@
+-- Фоновые задания импорта объявлений из CSV (см. internal/imports/runner.go)
+CREATE TABLE IF NOT EXISTS import_jobs (
+    id             BIGSERIAL PRIMARY KEY,
+    status         TEXT        NOT NULL DEFAULT 'queued',
+    file_name      TEXT        NOT NULL,
+    file_path      TEXT        NOT NULL,
+    batch_size     INTEGER     NOT NULL,
+    rows_processed INTEGER     NOT NULL DEFAULT 0,
+    rows_failed    INTEGER     NOT NULL DEFAULT 0,
+    error_samples  TEXT[]      NOT NULL DEFAULT '{}',
+    error          TEXT        NOT NULL DEFAULT '',
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT now(),
+    started_at     TIMESTAMPTZ,
+    finished_at    TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS import_jobs_queued_idx ON import_jobs (id) WHERE status = 'queued';
@@ -0,0 +1,95 @@
+package migrations
+
+// migrations содержит версионированную схему БД: пронумерованные up/down миграции встроены в бинарник
+// и применяются golang-migrate. Текущая версия хранится в таблице schema_migrations.
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/postgres" // Драйвер postgres:// для golang-migrate
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"io/fs"
+)
+
+//go:embed *.sql
+var files embed.FS
+
+// New создаёт мигратор для БД по строке подключения. Мигратор открывает собственное соединение,
+// которое закрывается вызовом Close.
+func New(connectionURL string) (*migrate.Migrate, error) {
+	sourceDriver, err := iofs.New(files, ".")
+	if err != nil {
+		return nil, fmt.Errorf("ошибка чтения миграций: %w", err)
+	}
+
+	migrator, err := migrate.NewWithSourceInstance("iofs", sourceDriver, connectionURL)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка подключения к БД для миграций: %w", err)
+	}
+
+	return migrator, nil
+}
+
+// LatestVersion возвращает номер последней встроенной миграции
+func LatestVersion() (uint, error) {
+	sourceDriver, err := iofs.New(files, ".")
+	if err != nil {
+		return 0, fmt.Errorf("ошибка чтения миграций: %w", err)
+	}
+	defer sourceDriver.Close()
+
+	return lastVersion(sourceDriver)
+}
+
+func lastVersion(sourceDriver source.Driver) (uint, error) {
+	version, err := sourceDriver.First()
+	if err != nil {
+		return 0, fmt.Errorf("ошибка чтения миграций: %w", err)
+	}
+
+	for {
+		next, err := sourceDriver.Next(version)
+		if errors.Is(err, fs.ErrNotExist) {
+			return version, nil
+		}
+		if err != nil {
+			return 0, fmt.Errorf("ошибка чтения миграций: %w", err)
+		}
+		version = next
+	}
+}
+
+// CheckVersion проверяет, что схема БД применена до последней встроенной миграции и не находится
+// в «грязном» состоянии после прерванной миграции
+func CheckVersion(connectionURL string) error {
+	latest, err := LatestVersion()
+	if err != nil {
+		return err
+	}
+
+	migrator, err := New(connectionURL)
+	if err != nil {
+		return err
+	}
+	defer migrator.Close()
+
+	version, dirty, err := migrator.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		return fmt.Errorf("миграции схемы БД не применялись, требуется версия %d", latest)
+	}
+	if err != nil {
+		return fmt.Errorf("ошибка получения версии схемы БД: %w", err)
+	}
+
+	if dirty {
+		return fmt.Errorf("миграция схемы БД до версии %d не завершилась, требуется ручное исправление", version)
+	}
+	if version < latest {
+		return fmt.Errorf("схема БД устарела: версия %d, требуется %d", version, latest)
+	}
+
+	return nil
+}
@@ -0,0 +1,28 @@
+package migrations
+
+import (
+	"io/fs"
+	"strings"
+	"testing"
+)
+
+func TestEmbeddedMigrations(t *testing.T) {
+	names, err := fs.Glob(files, "*.up.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	latest, err := LatestVersion()
+	if err != nil {
+		t.Fatalf("LatestVersion() error = %v", err)
+	}
+	if int(latest) != len(names) {
+		t.Errorf("LatestVersion() = %d, want %d (one version per up migration)", latest, len(names))
+	}
+	for _, name := range names {
+		down := strings.TrimSuffix(name, ".up.sql") + ".down.sql"
+		if _, err := fs.Stat(files, down); err != nil {
+			t.Errorf("migration %s has no down migration %s", name, down)
+		}
+	}
+}
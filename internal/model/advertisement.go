@@ -1,5 +1,11 @@
 package model
 
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
 type Advertisement struct {
 	Index        int     `db:"id" json:"id"`
 	Name         string  `db:"product_name" json:"product_name"`
@@ -14,3 +20,56 @@ type Advertisement struct {
 	Size         string  `db:"size" json:"size"`
 	Availability string  `db:"availability" json:"availability"`
 }
+
+// Availabilities — допустимые значения поля availability
+var Availabilities = []string{"in_stock", "limited_stock", "out_of_stock", "pre_order", "backorder", "discontinued"}
+
+var (
+	currencyPattern = regexp.MustCompile(`^[A-Z]{3}$`)
+	eanPattern      = regexp.MustCompile(`^[0-9]{8}$|^[0-9]{13}$`)
+)
+
+// Validate проверяет объявление перед записью и возвращает все найденные проблемы одной ошибкой
+func (advertisement *Advertisement) Validate() error {
+	var problems []string
+
+	if strings.TrimSpace(advertisement.Name) == "" {
+		problems = append(problems, "product_name обязателен")
+	}
+	if strings.TrimSpace(advertisement.Brand) == "" {
+		problems = append(problems, "brand обязателен")
+	}
+	if strings.TrimSpace(advertisement.Category) == "" {
+		problems = append(problems, "category обязателен")
+	}
+	if advertisement.Price < 0 {
+		problems = append(problems, "price не может быть отрицательной")
+	}
+	if !currencyPattern.MatchString(advertisement.Currency) {
+		problems = append(problems, "currency должна быть трёхбуквенным кодом ISO 4217, например USD")
+	}
+	if advertisement.Stock < 0 {
+		problems = append(problems, "stock не может быть отрицательным")
+	}
+	if advertisement.Ean != "" && !eanPattern.MatchString(advertisement.Ean) {
+		problems = append(problems, "ean должен состоять из 8 или 13 цифр")
+	}
+	if !isAvailability(advertisement.Availability) {
+		problems = append(problems, "availability должно быть одним из: "+strings.Join(Availabilities, ", "))
+	}
+
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, "; "))
+	}
+
+	return nil
+}
+
+func isAvailability(value string) bool {
+	for _, availability := range Availabilities {
+		if value == availability {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,52 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// AdvertisementEventType — вид изменения объявления в событии из Kafka
+type AdvertisementEventType string
+
+const (
+	AdvertisementCreated AdvertisementEventType = "create"
+	AdvertisementUpdated AdvertisementEventType = "update"
+	AdvertisementDeleted AdvertisementEventType = "delete"
+)
+
+// AdvertisementEvent — событие об изменении объявления.
+// Advertisement необязателен: если его нет, для create и update актуальная строка загружается из БД.
+type AdvertisementEvent struct {
+	Type          AdvertisementEventType `json:"type"`
+	ID            int                    `json:"id"`
+	Advertisement *Advertisement         `json:"advertisement,omitempty"`
+}
+
+// ParseAdvertisementEvent разбирает и проверяет событие об изменении объявления
+func ParseAdvertisementEvent(data []byte) (AdvertisementEvent, error) {
+	var event AdvertisementEvent
+	if err := json.Unmarshal(data, &event); err != nil {
+		return AdvertisementEvent{}, fmt.Errorf("ошибка парсинга события: %w", err)
+	}
+
+	switch event.Type {
+	case AdvertisementCreated, AdvertisementUpdated, AdvertisementDeleted:
+	default:
+		return AdvertisementEvent{}, fmt.Errorf("неизвестный тип события: %q", event.Type)
+	}
+
+	if event.ID <= 0 {
+		return AdvertisementEvent{}, fmt.Errorf("неверный id объявления в событии: %d", event.ID)
+	}
+
+	if event.Advertisement != nil {
+		if event.Advertisement.Index == 0 {
+			event.Advertisement.Index = event.ID
+		}
+		if event.Advertisement.Index != event.ID {
+			return AdvertisementEvent{}, fmt.Errorf("id события %d не совпадает с id объявления %d", event.ID, event.Advertisement.Index)
+		}
+	}
+
+	return event, nil
+}
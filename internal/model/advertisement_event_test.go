@@ -0,0 +1,37 @@
+package model
+
+import "testing"
+
+func TestParseAdvertisementEvent(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantID  int
+		wantErr bool
+	}{
+		{name: "delete", input: `{"type":"delete","id":7}`, wantID: 7},
+		{name: "payload without id", input: `{"type":"update","id":7,"advertisement":{"product_name":"Phone"}}`, wantID: 7},
+		{name: "unknown type", input: `{"type":"upsert","id":7}`, wantErr: true},
+		{name: "missing id", input: `{"type":"create"}`, wantErr: true},
+		{name: "payload id mismatch", input: `{"type":"update","id":7,"advertisement":{"id":8}}`, wantErr: true},
+		{name: "malformed", input: `{"type":`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event, err := ParseAdvertisementEvent([]byte(tt.input))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseAdvertisementEvent() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if event.ID != tt.wantID {
+				t.Errorf("ID = %d, want %d", event.ID, tt.wantID)
+			}
+			if event.Advertisement != nil && event.Advertisement.Index != tt.wantID {
+				t.Errorf("Advertisement.Index = %d, want %d", event.Advertisement.Index, tt.wantID)
+			}
+		})
+	}
+}
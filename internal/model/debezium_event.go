@@ -0,0 +1,163 @@
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// EventFormat — формат сообщений в топике изменений объявлений
+type EventFormat string
+
+const (
+	// EventFormatNative — события AdvertisementEvent, публикуемые приложением
+	EventFormatNative EventFormat = "native"
+	// EventFormatDebezium — CDC-события Debezium по таблице advertisements
+	EventFormatDebezium EventFormat = "debezium"
+)
+
+// ParseEventFormat проверяет название формата событий. Пустая строка означает EventFormatNative.
+func ParseEventFormat(raw string) (EventFormat, error) {
+	switch EventFormat(raw) {
+	case "", EventFormatNative:
+		return EventFormatNative, nil
+	case EventFormatDebezium:
+		return EventFormatDebezium, nil
+	default:
+		return "", fmt.Errorf("неизвестный формат событий: %s (допустимо native, debezium)", raw)
+	}
+}
+
+// debeziumPayload — конверт изменения строки Debezium
+type debeziumPayload struct {
+	Before json.RawMessage `json:"before"`
+	After  json.RawMessage `json:"after"`
+	Op     string          `json:"op"`
+}
+
+// debeziumRow — строка таблицы advertisements в событии Debezium.
+// Цена (NUMERIC) приходит числом или строкой в зависимости от decimal.handling.mode коннектора.
+type debeziumRow struct {
+	Advertisement
+	Price     json.RawMessage `json:"price"`
+	DeletedAt json.RawMessage `json:"deleted_at"`
+}
+
+// ParseDebeziumEvent преобразует сообщение Debezium в событие об изменении объявления.
+// Поддерживаются операции c, u, r (снимок) и d, а также tombstone-сообщения с пустым значением,
+// которые Debezium публикует после удаления: они тоже превращаются в удаление по id из ключа.
+// Строка с заполненным deleted_at (мягкое удаление) также превращается в удаление.
+// Конверт принимается как со схемой ({"schema": ..., "payload": ...}), так и без неё.
+func ParseDebeziumEvent(key []byte, value []byte) (AdvertisementEvent, error) {
+	if len(value) == 0 || bytes.Equal(bytes.TrimSpace(value), []byte("null")) {
+		id, err := parseDebeziumKey(key)
+		if err != nil {
+			return AdvertisementEvent{}, err
+		}
+		return AdvertisementEvent{Type: AdvertisementDeleted, ID: id}, nil
+	}
+
+	var payload debeziumPayload
+	if err := json.Unmarshal(unwrapDebeziumPayload(value), &payload); err != nil {
+		return AdvertisementEvent{}, fmt.Errorf("ошибка парсинга события Debezium: %w", err)
+	}
+
+	var event AdvertisementEvent
+	var row []byte
+	switch payload.Op {
+	case "c":
+		event.Type, row = AdvertisementCreated, payload.After
+	case "u", "r":
+		event.Type, row = AdvertisementUpdated, payload.After
+	case "d":
+		event.Type, row = AdvertisementDeleted, payload.Before
+	default:
+		return AdvertisementEvent{}, fmt.Errorf("неподдерживаемая операция Debezium: %q", payload.Op)
+	}
+
+	if isJSONNull(row) {
+		// При REPLICA IDENTITY DEFAULT в удалении может не быть before, тогда id берётся из ключа
+		if event.Type != AdvertisementDeleted {
+			return AdvertisementEvent{}, fmt.Errorf("в событии Debezium %q нет поля after", payload.Op)
+		}
+		id, err := parseDebeziumKey(key)
+		if err != nil {
+			return AdvertisementEvent{}, err
+		}
+		event.ID = id
+		return event, nil
+	}
+
+	advertisement, softDeleted, err := parseDebeziumRow(row)
+	if err != nil {
+		return AdvertisementEvent{}, err
+	}
+	// Мягкое удаление приходит как update с заполненным deleted_at
+	if softDeleted {
+		event.Type = AdvertisementDeleted
+	}
+	if advertisement.Index <= 0 {
+		return AdvertisementEvent{}, fmt.Errorf("неверный id объявления в событии Debezium: %d", advertisement.Index)
+	}
+
+	event.ID = advertisement.Index
+	if event.Type != AdvertisementDeleted {
+		event.Advertisement = &advertisement
+	}
+
+	return event, nil
+}
+
+// unwrapDebeziumPayload возвращает содержимое payload, если сообщение сериализовано вместе со схемой
+func unwrapDebeziumPayload(data []byte) []byte {
+	var envelope struct {
+		Schema  json.RawMessage `json:"schema"`
+		Payload json.RawMessage `json:"payload"`
+	}
+	if err := json.Unmarshal(data, &envelope); err == nil && envelope.Schema != nil && envelope.Payload != nil {
+		return envelope.Payload
+	}
+	return data
+}
+
+func parseDebeziumKey(key []byte) (int, error) {
+	if len(key) == 0 {
+		return 0, fmt.Errorf("в событии Debezium нет ни значения, ни ключа")
+	}
+
+	var row struct {
+		ID int `json:"id"`
+	}
+	if err := json.Unmarshal(unwrapDebeziumPayload(key), &row); err != nil {
+		return 0, fmt.Errorf("ошибка парсинга ключа Debezium: %w", err)
+	}
+	if row.ID <= 0 {
+		return 0, fmt.Errorf("неверный id объявления в ключе Debezium: %d", row.ID)
+	}
+
+	return row.ID, nil
+}
+
+// parseDebeziumRow разбирает строку таблицы; softDeleted = true, если у строки заполнен deleted_at
+func parseDebeziumRow(data []byte) (advertisement Advertisement, softDeleted bool, err error) {
+	var row debeziumRow
+	if err := json.Unmarshal(data, &row); err != nil {
+		return Advertisement{}, false, fmt.Errorf("ошибка парсинга строки Debezium: %w", err)
+	}
+
+	advertisement = row.Advertisement
+	if !isJSONNull(row.Price) {
+		price, err := strconv.ParseFloat(string(bytes.Trim(row.Price, `"`)), 64)
+		if err != nil {
+			return Advertisement{}, false, fmt.Errorf("неверная цена %s в событии Debezium (нужен decimal.handling.mode=double или string): %w", row.Price, err)
+		}
+		advertisement.Price = price
+	}
+
+	return advertisement, !isJSONNull(row.DeletedAt), nil
+}
+
+func isJSONNull(data json.RawMessage) bool {
+	return len(data) == 0 || bytes.Equal(data, []byte("null"))
+}
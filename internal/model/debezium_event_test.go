@@ -0,0 +1,134 @@
+package model
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseDebeziumEvent(t *testing.T) {
+	tests := []struct {
+		name    string
+		key     string
+		value   string
+		want    AdvertisementEvent
+		wantErr bool
+	}{
+		{
+			name:  "create with schema",
+			key:   `{"schema":{},"payload":{"id":7}}`,
+			value: `{"schema":{},"payload":{"before":null,"after":{"id":7,"product_name":"Phone","price":"19.90","stock":3},"op":"c"}}`,
+			want: AdvertisementEvent{Type: AdvertisementCreated, ID: 7,
+				Advertisement: &Advertisement{Index: 7, Name: "Phone", Price: 19.9, Stock: 3}},
+		},
+		{
+			name:  "snapshot read",
+			value: `{"before":null,"after":{"id":8,"brand":"Acme","price":5},"op":"r"}`,
+			want: AdvertisementEvent{Type: AdvertisementUpdated, ID: 8,
+				Advertisement: &Advertisement{Index: 8, Brand: "Acme", Price: 5}},
+		},
+		{
+			name:  "delete",
+			value: `{"before":{"id":9,"product_name":"Old"},"after":null,"op":"d"}`,
+			want:  AdvertisementEvent{Type: AdvertisementDeleted, ID: 9},
+		},
+		{
+			name:  "delete without before",
+			key:   `{"id":10}`,
+			value: `{"before":null,"after":null,"op":"d"}`,
+			want:  AdvertisementEvent{Type: AdvertisementDeleted, ID: 10},
+		},
+		{
+			name:  "delete with schema",
+			key:   `{"schema":{},"payload":{"id":14}}`,
+			value: `{"schema":{},"payload":{"before":{"id":14,"product_name":"Old","price":"1.50"},"after":null,"op":"d"}}`,
+			want:  AdvertisementEvent{Type: AdvertisementDeleted, ID: 14},
+		},
+		{
+			name:  "delete without before, key with schema",
+			key:   `{"schema":{},"payload":{"id":15}}`,
+			value: `{"schema":{},"payload":{"before":null,"after":null,"op":"d"}}`,
+			want:  AdvertisementEvent{Type: AdvertisementDeleted, ID: 15},
+		},
+		{
+			name:    "delete without before and key",
+			value:   `{"before":null,"after":null,"op":"d"}`,
+			wantErr: true,
+		},
+		{
+			name:    "delete with invalid id",
+			value:   `{"before":{"id":0},"after":null,"op":"d"}`,
+			wantErr: true,
+		},
+		{
+			name:    "update without after",
+			key:     `{"id":16}`,
+			value:   `{"before":{"id":16},"after":null,"op":"u"}`,
+			wantErr: true,
+		},
+		{
+			name:  "soft delete",
+			value: `{"before":{"id":13},"after":{"id":13,"product_name":"Old","deleted_at":"2025-01-02T03:04:05Z"},"op":"u"}`,
+			want:  AdvertisementEvent{Type: AdvertisementDeleted, ID: 13},
+		},
+		{
+			name: "tombstone",
+			key:  `{"id":11}`,
+			want: AdvertisementEvent{Type: AdvertisementDeleted, ID: 11},
+		},
+		{
+			name:  "tombstone with null value",
+			key:   `{"id":17}`,
+			value: `null`,
+			want:  AdvertisementEvent{Type: AdvertisementDeleted, ID: 17},
+		},
+		{
+			name: "tombstone with schema key",
+			key:  `{"schema":{},"payload":{"id":18}}`,
+			want: AdvertisementEvent{Type: AdvertisementDeleted, ID: 18},
+		},
+		{
+			name:    "tombstone without key",
+			wantErr: true,
+		},
+		{
+			name:    "tombstone with malformed key",
+			key:     `{"id":`,
+			wantErr: true,
+		},
+		{
+			name:    "tombstone with invalid id",
+			key:     `{"id":0}`,
+			wantErr: true,
+		},
+		{
+			name:    "precise decimal",
+			value:   `{"after":{"id":12,"price":"B9o="},"op":"u"}`,
+			wantErr: true,
+		},
+		{
+			name:    "truncate",
+			value:   `{"op":"t"}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var key, value []byte
+			if tt.key != "" {
+				key = []byte(tt.key)
+			}
+			if tt.value != "" {
+				value = []byte(tt.value)
+			}
+
+			got, err := ParseDebeziumEvent(key, value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseDebeziumEvent() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseDebeziumEvent() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package model
+
+import (
+	"github.com/lib/pq"
+	"time"
+)
+
+// ImportJobStatus — состояние задания импорта CSV
+type ImportJobStatus string
+
+const (
+	ImportQueued    ImportJobStatus = "queued"
+	ImportRunning   ImportJobStatus = "running"
+	ImportSucceeded ImportJobStatus = "succeeded"
+	// ImportPartial — часть пакетов не сохранена, их строки перечислены в ErrorSamples
+	ImportPartial   ImportJobStatus = "partial"
+	ImportFailed    ImportJobStatus = "failed"
+	ImportCancelled ImportJobStatus = "cancelled"
+)
+
+// Finished сообщает, что задание больше не будет выполняться
+func (status ImportJobStatus) Finished() bool {
+	return status == ImportSucceeded || status == ImportPartial || status == ImportFailed || status == ImportCancelled
+}
+
+// ImportJob — задание импорта объявлений из загруженного CSV-файла
+type ImportJob struct {
+	ID     int64           `db:"id" json:"id"`
+	Status ImportJobStatus `db:"status" json:"status"`
+	// FileName — имя файла, под которым он был загружен
+	FileName string `db:"file_name" json:"file_name"`
+	// FilePath — путь к сохранённой копии файла на сервере
+	FilePath      string         `db:"file_path" json:"-"`
+	BatchSize     int            `db:"batch_size" json:"batch_size"`
+	RowsProcessed int            `db:"rows_processed" json:"rows_processed"`
+	RowsFailed    int            `db:"rows_failed" json:"rows_failed"`
+	ErrorSamples  pq.StringArray `db:"error_samples" json:"error_samples"`
+	Error         string         `db:"error" json:"error,omitempty"`
+	CreatedAt     time.Time      `db:"created_at" json:"created_at"`
+	StartedAt     *time.Time     `db:"started_at" json:"started_at,omitempty"`
+	FinishedAt    *time.Time     `db:"finished_at" json:"finished_at,omitempty"`
+}
@@ -1,10 +1,117 @@
 package model
 
+import (
+	"fmt"
+	"strings"
+)
+
 type SearchFilters struct {
-	ProductName string   `json:"product_name"`
-	Brand       string   `json:"brand"`
-	Category    string   `json:"category"`
-	MinPrice    *float64 `json:"min_price"`
-	MaxPrice    *float64 `json:"max_price"`
-	InStockOnly bool     `json:"in_stock_only"`
+	// Query — свободный текст, который ищется сразу по названию, бренду, категории и описанию
+	Query       string    `json:"q"`
+	ProductName string    `json:"product_name"`
+	Brand       string    `json:"brand"`
+	Category    string    `json:"category"`
+	MinPrice    *float64  `json:"min_price"`
+	MaxPrice    *float64  `json:"max_price"`
+	InStockOnly bool      `json:"in_stock_only"`
+	Sort        SortOrder `json:"sort"`
+	// WithFacets включает расчёт фасетов (агрегаций) по текущему запросу
+	WithFacets bool `json:"with_facets"`
+	// FacetSelections — выбранные значения фасетов: имя фасета -> точные значения.
+	// Применяются как post_filter, поэтому не сужают счётчики своего же фасета.
+	FacetSelections map[Facet][]string `json:"facet_selections"`
+	// PriceAggregations задаёт, какие агрегации по цене посчитать для текущей выдачи
+	PriceAggregations PriceAggregationOptions `json:"price_aggregations"`
+	// Fuzziness — допустимое число опечаток в текстовых фильтрах ("AUTO", "0", "1", "2").
+	// Пустая строка отключает нечёткий поиск.
+	Fuzziness string `json:"fuzziness"`
+	// WithDidYouMean включает подсказку исправления запроса, если найдено слишком мало результатов
+	WithDidYouMean bool `json:"with_did_you_mean"`
+}
+
+// DidYouMeanMaxHits — подсказка исправления возвращается, только если найдено не больше стольких объявлений
+const DidYouMeanMaxHits = 3
+
+// ParseFuzziness проверяет значение нечёткости в формате Elasticsearch
+func ParseFuzziness(raw string) (string, error) {
+	switch strings.ToUpper(raw) {
+	case "", "0":
+		return "", nil
+	case "AUTO":
+		return "AUTO", nil
+	case "1", "2":
+		return raw, nil
+	default:
+		return "", fmt.Errorf("неверное значение fuzziness: %s (допустимо AUTO, 0, 1, 2)", raw)
+	}
+}
+
+const (
+	// MinPriceHistogramInterval — минимальный шаг гистограммы цен
+	MinPriceHistogramInterval = 1.0
+	// MaxPriceHistogramBuckets — наибольшее число корзин гистограммы цен. Если запрошенный шаг
+	// на разбросе цен выдачи даёт больше корзин, шаг увеличивается: иначе Elasticsearch отклонит
+	// весь поиск из-за search.max_buckets.
+	MaxPriceHistogramBuckets = 1000
+)
+
+// PriceAggregationOptions — запрос статистики и распределения цен.
+// Гистограмма и пользовательские диапазоны взаимоисключающие.
+type PriceAggregationOptions struct {
+	WithStats bool `json:"with_stats"`
+	// HistogramInterval > 0 включает гистограмму с фиксированным шагом
+	HistogramInterval float64      `json:"histogram_interval"`
+	Ranges            []PriceRange `json:"ranges"`
+}
+
+// Enabled сообщает, запрошена ли хотя бы одна агрегация по цене
+func (options PriceAggregationOptions) Enabled() bool {
+	return options.WithStats || options.HistogramInterval > 0 || len(options.Ranges) > 0
+}
+
+// PriceRange — диапазон цен [From, To). Отсутствующая граница означает открытый диапазон.
+type PriceRange struct {
+	From *float64 `json:"from,omitempty"`
+	To   *float64 `json:"to,omitempty"`
+}
+
+// Facet — имя фасета для навигации по каталогу
+type Facet string
+
+const (
+	FacetBrand        Facet = "brand"
+	FacetCategory     Facet = "category"
+	FacetColor        Facet = "color"
+	FacetSize         Facet = "size"
+	FacetAvailability Facet = "availability"
+)
+
+// Facets — все поддерживаемые фасеты в порядке отображения
+var Facets = []Facet{FacetBrand, FacetCategory, FacetColor, FacetSize, FacetAvailability}
+
+// SortOrder — порядок сортировки результатов поиска
+type SortOrder string
+
+const (
+	SortRelevance SortOrder = "relevance"
+	SortPriceAsc  SortOrder = "price_asc"
+	SortPriceDesc SortOrder = "price_desc"
+	SortStockAsc  SortOrder = "stock_asc"
+	SortStockDesc SortOrder = "stock_desc"
+	SortNameAsc   SortOrder = "name_asc"
+	SortNameDesc  SortOrder = "name_desc"
+)
+
+// ParseSortOrder проверяет ключ сортировки. Пустая строка означает сортировку по релевантности.
+func ParseSortOrder(raw string) (SortOrder, error) {
+	if raw == "" {
+		return SortRelevance, nil
+	}
+
+	switch order := SortOrder(raw); order {
+	case SortRelevance, SortPriceAsc, SortPriceDesc, SortStockAsc, SortStockDesc, SortNameAsc, SortNameDesc:
+		return order, nil
+	default:
+		return "", fmt.Errorf("неизвестный ключ сортировки: %s", raw)
+	}
 }
@@ -0,0 +1,67 @@
+package model
+
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+	// MaxResultWindow совпадает с index.max_result_window Elasticsearch по умолчанию
+	MaxResultWindow = 10000
+)
+
+// Pagination описывает окно выдачи в терминах from/size Elasticsearch
+type Pagination struct {
+	From int `json:"from"`
+	Size int `json:"size"`
+}
+
+// Page возвращает номер страницы (начиная с 1), к которой относится окно
+func (pagination Pagination) Page() int {
+	if pagination.Size <= 0 {
+		return 1
+	}
+	return pagination.From/pagination.Size + 1
+}
+
+// SearchResult — конверт ответа поиска: найденные объявления и сведения о пагинации
+type SearchResult struct {
+	Items    []Advertisement `json:"items"`
+	Total    int64           `json:"total"`
+	Page     int             `json:"page"`
+	PageSize int             `json:"page_size"`
+	HasMore  bool            `json:"has_more"`
+	// Facets заполняется только если фасеты были запрошены
+	Facets map[Facet][]FacetBucket `json:"facets,omitempty"`
+	// Price заполняется только если были запрошены агрегации по цене
+	Price *PriceAggregationResult `json:"price,omitempty"`
+	// DidYouMean — исправленные значения текстовых параметров: имя параметра -> вариант написания
+	DidYouMean map[string]string `json:"did_you_mean,omitempty"`
+}
+
+// PriceAggregationResult — статистика и распределение цен по текущей выдаче
+type PriceAggregationResult struct {
+	Stats *PriceStats `json:"stats,omitempty"`
+	// HistogramInterval — фактический шаг гистограммы; может быть больше запрошенного,
+	// если разброс цен не укладывается в MaxPriceHistogramBuckets корзин
+	HistogramInterval float64       `json:"histogram_interval,omitempty"`
+	Histogram         []PriceBucket `json:"histogram,omitempty"`
+	Ranges            []PriceBucket `json:"ranges,omitempty"`
+}
+
+// PriceStats — границы и среднее цены. Для пустой выдачи Min, Max и Avg равны nil.
+type PriceStats struct {
+	Count int64    `json:"count"`
+	Min   *float64 `json:"min"`
+	Max   *float64 `json:"max"`
+	Avg   *float64 `json:"avg"`
+}
+
+// PriceBucket — количество объявлений с ценой в диапазоне [From, To)
+type PriceBucket struct {
+	PriceRange
+	Count int64 `json:"count"`
+}
+
+// FacetBucket — значение фасета и количество объявлений с ним
+type FacetBucket struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
@@ -0,0 +1,20 @@
+package model
+
+const (
+	DefaultSuggestLimit = 10
+	MaxSuggestLimit     = 50
+)
+
+// SuggestRequest — запрос автодополнения по префиксу
+type SuggestRequest struct {
+	Prefix string `json:"prefix"`
+	// Category ограничивает подсказки одной категорией, пустая строка — без ограничения
+	Category string `json:"category"`
+	Limit    int    `json:"limit"`
+}
+
+// SuggestResult — уникальные варианты дополнения названий товаров и брендов
+type SuggestResult struct {
+	ProductNames []string `json:"product_names"`
+	Brands       []string `json:"brands"`
+}
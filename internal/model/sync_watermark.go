@@ -0,0 +1,23 @@
+package model
+
+import "time"
+
+// SyncWatermark — позиция инкрементальной синхронизации: все строки с (updated_at, id)
+// не больше этой пары уже проиндексированы
+type SyncWatermark struct {
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+	ID        int       `db:"last_id" json:"last_id"`
+}
+
+// AdvertisementChange — изменённое объявление вместе с временем изменения.
+// DeletedAt заполнен, если объявление мягко удалено.
+type AdvertisementChange struct {
+	Advertisement
+	UpdatedAt time.Time  `db:"updated_at" json:"updated_at"`
+	DeletedAt *time.Time `db:"deleted_at" json:"deleted_at,omitempty"`
+}
+
+// Watermark возвращает позицию синхронизации сразу после этого изменения
+func (change *AdvertisementChange) Watermark() SyncWatermark {
+	return SyncWatermark{UpdatedAt: change.UpdatedAt, ID: change.Index}
+}
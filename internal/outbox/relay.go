@@ -0,0 +1,125 @@
+package outbox
+
+// relay публикует события из таблицы advertisement_outbox в Kafka в порядке их записи.
+// Ключ сообщения — id объявления, поэтому все события одного объявления попадают в одну партицию
+// и читаются consumer'ом в том же порядке. Запись отмечается отправленной только после подтверждения
+// доставки; при сбое неотправленный хвост пакета публикуется снова, поэтому возможны дубли, но не потери.
+
+import (
+	"SearchService/internal/repository"
+	"context"
+	"fmt"
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"log"
+	"strconv"
+	"time"
+)
+
+// Options — настройки relay
+type Options struct {
+	// BatchSize — сколько записей outbox публикуется за один проход
+	BatchSize int
+	// PollInterval — пауза перед следующим проходом, когда outbox пуст или публикация не удалась
+	PollInterval time.Duration
+}
+
+// DefaultOptions используются для полей Options, оставленных нулевыми
+var DefaultOptions = Options{
+	BatchSize:    500,
+	PollInterval: time.Second,
+}
+
+// messageProducer — отправка сообщений в Kafka, реализуется *kafka.Producer
+type messageProducer interface {
+	Produce(message *kafka.Message, deliveryChan chan kafka.Event) error
+}
+
+type Relay struct {
+	repo     *repository.OutboxRepository
+	producer messageProducer
+	topic    string
+	options  Options
+}
+
+func NewRelay(repo *repository.OutboxRepository, producer *kafka.Producer, topic string, options Options) *Relay {
+	if options.BatchSize <= 0 {
+		options.BatchSize = DefaultOptions.BatchSize
+	}
+	if options.PollInterval <= 0 {
+		options.PollInterval = DefaultOptions.PollInterval
+	}
+
+	return &Relay{repo: repo, producer: producer, topic: topic, options: options}
+}
+
+// Run публикует события до отмены ctx
+func (r *Relay) Run(ctx context.Context) {
+	for ctx.Err() == nil {
+		published, err := r.repo.ProcessUnsent(r.options.BatchSize, r.publish)
+		if err != nil {
+			log.Printf("ошибка публикации событий outbox (опубликовано %d): %v", published, err)
+		}
+		if published > 0 {
+			log.Printf("Опубликовано %d событий outbox", published)
+		}
+
+		// Полный пакет означает, что в outbox, скорее всего, есть ещё записи
+		if err == nil && published == r.options.BatchSize {
+			continue
+		}
+
+		select {
+		case <-time.After(r.options.PollInterval):
+		case <-ctx.Done():
+		}
+	}
+}
+
+// publish отправляет записи в Kafka и возвращает длину начального отрезка records,
+// доставка которого подтверждена
+func (r *Relay) publish(records []repository.OutboxRecord) (int, error) {
+	deliveries := make(chan kafka.Event, len(records))
+
+	produced := 0
+	var produceErr error
+	for i, record := range records {
+		err := r.producer.Produce(&kafka.Message{
+			TopicPartition: kafka.TopicPartition{Topic: &r.topic, Partition: kafka.PartitionAny},
+			Key:            []byte(strconv.Itoa(record.AdvertisementID)),
+			Value:          record.Payload,
+			Opaque:         i,
+		}, deliveries)
+		if err != nil {
+			produceErr = fmt.Errorf("ошибка отправки события outbox %d: %w", record.ID, err)
+			break
+		}
+		produced++
+	}
+
+	delivered := make([]bool, produced)
+	var deliveryErr error
+	for i := 0; i < produced; i++ {
+		message, ok := (<-deliveries).(*kafka.Message)
+		if !ok {
+			continue
+		}
+		index, _ := message.Opaque.(int)
+		if message.TopicPartition.Error != nil {
+			if deliveryErr == nil {
+				deliveryErr = fmt.Errorf("ошибка доставки события outbox %d: %w", records[index].ID, message.TopicPartition.Error)
+			}
+			continue
+		}
+		delivered[index] = true
+	}
+
+	confirmed := 0
+	for confirmed < produced && delivered[confirmed] {
+		confirmed++
+	}
+
+	if deliveryErr != nil {
+		return confirmed, deliveryErr
+	}
+	return confirmed, produceErr
+}
@@ -0,0 +1,99 @@
+package outbox
+
+import (
+	"SearchService/internal/repository"
+	"errors"
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"strconv"
+	"testing"
+)
+
+// memoryProducer подтверждает доставку сообщений в обратном порядке, как может ответить брокер
+// по разным партициям. Сообщения с индексами из failDelivery доставляются с ошибкой,
+// а Produce для сообщения с индексом rejectAt возвращает ошибку.
+type memoryProducer struct {
+	messages     []*kafka.Message
+	failDelivery map[int]bool
+	rejectAt     int
+	expected     int
+}
+
+func (producer *memoryProducer) Produce(message *kafka.Message, deliveryChan chan kafka.Event) error {
+	index := message.Opaque.(int)
+	if index == producer.rejectAt {
+		producer.flush(deliveryChan)
+		return errors.New("queue full")
+	}
+
+	producer.messages = append(producer.messages, message)
+	if len(producer.messages) == producer.expected {
+		producer.flush(deliveryChan)
+	}
+	return nil
+}
+
+func (producer *memoryProducer) flush(deliveryChan chan kafka.Event) {
+	for i := len(producer.messages) - 1; i >= 0; i-- {
+		delivered := *producer.messages[i]
+		if producer.failDelivery[delivered.Opaque.(int)] {
+			delivered.TopicPartition.Error = errors.New("not enough replicas")
+		}
+		deliveryChan <- &delivered
+	}
+}
+
+func TestRelayPublish(t *testing.T) {
+	records := []repository.OutboxRecord{
+		{ID: 11, AdvertisementID: 7, Payload: []byte(`{"type":"create","id":7}`)},
+		{ID: 12, AdvertisementID: 8, Payload: []byte(`{"type":"update","id":8}`)},
+		{ID: 13, AdvertisementID: 7, Payload: []byte(`{"type":"delete","id":7}`)},
+	}
+
+	tests := []struct {
+		name          string
+		failDelivery  map[int]bool
+		rejectAt      int
+		wantConfirmed int
+		wantErr       bool
+	}{
+		{name: "all delivered", rejectAt: -1, wantConfirmed: 3},
+		{
+			// Записи после недоставленной не подтверждаются, даже если доставлены: порядок важнее дублей
+			name:          "middle delivery failed",
+			failDelivery:  map[int]bool{1: true},
+			rejectAt:      -1,
+			wantConfirmed: 1,
+			wantErr:       true,
+		},
+		{name: "first delivery failed", failDelivery: map[int]bool{0: true}, rejectAt: -1, wantConfirmed: 0, wantErr: true},
+		{name: "produce rejected", rejectAt: 2, wantConfirmed: 2, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			producer := &memoryProducer{failDelivery: tt.failDelivery, rejectAt: tt.rejectAt, expected: len(records)}
+			relay := &Relay{producer: producer, topic: "advertisements.changes"}
+
+			confirmed, err := relay.publish(records)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("publish() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if confirmed != tt.wantConfirmed {
+				t.Errorf("publish() = %d, want %d", confirmed, tt.wantConfirmed)
+			}
+
+			for i, message := range producer.messages {
+				if *message.TopicPartition.Topic != "advertisements.changes" {
+					t.Errorf("message %d topic = %s, want advertisements.changes", i, *message.TopicPartition.Topic)
+				}
+				// Ключ — id объявления, чтобы события одного объявления шли через одну партицию
+				if want := strconv.Itoa(records[i].AdvertisementID); string(message.Key) != want {
+					t.Errorf("message %d key = %s, want %s", i, message.Key, want)
+				}
+				if string(message.Value) != string(records[i].Payload) {
+					t.Errorf("message %d value = %s, want %s", i, message.Value, records[i].Payload)
+				}
+			}
+		})
+	}
+}
@@ -6,5 +6,11 @@ import "SearchService/internal/model"
 
 type AdvertisementBatchLoader interface {
 	GetAdvertisementsBatch(limit int, offset int) ([]model.Advertisement, error)
+	// GetAdvertisementsAfter возвращает до limit объявлений с id > lastID в порядке возрастания id.
+	// В отличие от GetAdvertisementsBatch, стоимость не растёт с глубиной выгрузки,
+	// а вставки и удаления во время выгрузки не приводят к пропускам и дублям.
+	GetAdvertisementsAfter(lastID int, limit int) ([]model.Advertisement, error)
+	// GetAdvertisementById возвращает объявление по id; мягко удалённые объявления, как и в
+	// остальных методах, не возвращаются
 	GetAdvertisementById(id int) (model.Advertisement, error)
 }
@@ -0,0 +1,27 @@
+package ports
+
+import (
+	"SearchService/internal/model"
+	"time"
+)
+
+type AdvertisementChangeLoader interface {
+	// GetAdvertisementsChangedSince возвращает до limit объявлений, у которых пара (updated_at, id)
+	// больше after, а updated_at не позже now() - lag по часам БД, в порядке возрастания (updated_at, id)
+	GetAdvertisementsChangedSince(after model.SyncWatermark, lag time.Duration, limit int) ([]model.AdvertisementChange, error)
+	// GetDatabaseTime возвращает текущее время по часам БД, в которых выставляется updated_at
+	GetDatabaseTime() (time.Time, error)
+}
+
+// AdvertisementReindexLoader — источник переиндексации: полная выгрузка и изменения, внесённые во время неё
+type AdvertisementReindexLoader interface {
+	AdvertisementBatchLoader
+	AdvertisementChangeLoader
+}
+
+// SyncWatermarkStore хранит позиции инкрементальной синхронизации по имени
+type SyncWatermarkStore interface {
+	// GetWatermark возвращает сохранённую позицию; found = false, если синхронизация ещё не выполнялась
+	GetWatermark(name string) (watermark model.SyncWatermark, found bool, err error)
+	SaveWatermark(name string, watermark model.SyncWatermark) error
+}
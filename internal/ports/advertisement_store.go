@@ -0,0 +1,21 @@
+package ports
+
+import "SearchService/internal/model"
+
+// AdvertisementStore — хранилище объявлений, являющееся источником истины.
+// Update, Patch и Delete возвращают ошибку, оборачивающую sql.ErrNoRows, если объявления нет.
+type AdvertisementStore interface {
+	Save(advertisement *model.Advertisement) (*model.Advertisement, error)
+	GetAdvertisementById(id int) (model.Advertisement, error)
+	Update(advertisement *model.Advertisement) (*model.Advertisement, error)
+	// Patch изменяет объявление функцией apply атомарно относительно других изменений того же объявления.
+	// Ошибка apply отменяет изменение и возвращается без обёртки.
+	Patch(id int, apply func(advertisement *model.Advertisement) error) (*model.Advertisement, error)
+	Delete(id int) error
+}
+
+// AdvertisementIndexer синхронизирует документ объявления в поисковом индексе
+type AdvertisementIndexer interface {
+	IndexAdvertisement(advertisement *model.Advertisement) error
+	DeleteAdvertisement(id int) error
+}
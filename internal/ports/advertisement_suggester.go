@@ -0,0 +1,10 @@
+package ports
+
+import (
+	"SearchService/internal/model"
+	"context"
+)
+
+type AdvertisementSuggester interface {
+	SuggestAdvertisements(ctx context.Context, request model.SuggestRequest) (model.SuggestResult, error)
+}
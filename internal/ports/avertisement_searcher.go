@@ -6,5 +6,5 @@ import (
 )
 
 type AdvertisementSearcher interface {
-	SearchAdvertisements(ctx context.Context, filters model.SearchFilters) ([]model.Advertisement, error)
+	SearchAdvertisements(ctx context.Context, filters model.SearchFilters, pagination model.Pagination) (model.SearchResult, error)
 }
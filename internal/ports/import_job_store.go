@@ -0,0 +1,18 @@
+package ports
+
+import "SearchService/internal/model"
+
+type ImportJobStore interface {
+	// CreateImportJob сохраняет новое задание в состоянии queued и заполняет его id и created_at
+	CreateImportJob(job *model.ImportJob) error
+	GetImportJob(id int64) (model.ImportJob, error)
+	// ClaimNextImportJob переводит самое старое задание из queued в running и возвращает его.
+	// found = false, если заданий в очереди нет.
+	ClaimNextImportJob() (job model.ImportJob, found bool, err error)
+	SaveImportProgress(id int64, rowsProcessed int, rowsFailed int, errorSamples []string) error
+	FinishImportJob(id int64, status model.ImportJobStatus, message string) error
+	// CancelQueuedImportJob отменяет задание, если оно ещё в очереди; cancelled = false, если оно уже запущено или завершено
+	CancelQueuedImportJob(id int64) (cancelled bool, err error)
+	// FailInterruptedImportJobs завершает с ошибкой задания, оставшиеся в running после остановки сервера
+	FailInterruptedImportJobs(message string) (int, error)
+}
@@ -4,8 +4,16 @@ import (
 	"SearchService/internal"
 	"SearchService/internal/model"
 	"fmt"
+	"github.com/jmoiron/sqlx"
+	"time"
 )
 
+// advertisementColumns — все колонки таблицы advertisements, соответствующие полям model.Advertisement
+const advertisementColumns = `id, product_name, description, brand, category, price, currency, stock, ean, color, size, availability`
+
+// notDeleted — условие, исключающее мягко удалённые объявления; добавляется во все выборки для чтения
+const notDeleted = `deleted_at IS NULL`
+
 type AdvertisementRepository struct {
 	Database *internal.Database
 }
@@ -14,6 +22,7 @@ func NewAdvertisementRepository(database *internal.Database) *AdvertisementRepos
 	return &AdvertisementRepository{Database: database}
 }
 
+// Save добавляет объявление и в той же транзакции записывает событие create в outbox
 func (repo *AdvertisementRepository) Save(advertisement *model.Advertisement) (*model.Advertisement, error) {
 	query := `
 		INSERT INTO advertisements (
@@ -25,17 +34,144 @@ func (repo *AdvertisementRepository) Save(advertisement *model.Advertisement) (*
 		RETURNING id
 	`
 
-	if err := repo.Database.DB.QueryRowx(query, advertisement).Scan(&advertisement.Index); err != nil {
+	err := repo.inTransaction(func(tx *sqlx.Tx) error {
+		statement, err := tx.PrepareNamed(query)
+		if err != nil {
+			return err
+		}
+		defer statement.Close()
+
+		if err := statement.Get(&advertisement.Index, advertisement); err != nil {
+			return err
+		}
+
+		return WriteOutboxEvents(tx, []model.AdvertisementEvent{
+			{Type: model.AdvertisementCreated, ID: advertisement.Index, Advertisement: advertisement},
+		})
+	})
+	if err != nil {
 		return nil, fmt.Errorf("ошибка вставки объявления в БД: %w", err)
 	}
 
 	return advertisement, nil
 }
 
+// Update перезаписывает объявление с id advertisement.Index и в той же транзакции записывает событие update в outbox.
+// Если объявления нет, возвращается ошибка, оборачивающая sql.ErrNoRows.
+func (repo *AdvertisementRepository) Update(advertisement *model.Advertisement) (*model.Advertisement, error) {
+	err := repo.inTransaction(func(tx *sqlx.Tx) error {
+		return updateAdvertisement(tx, advertisement)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ошибка обновления объявления %d в БД: %w", advertisement.Index, err)
+	}
+
+	return advertisement, nil
+}
+
+// Patch загружает объявление с блокировкой строки, изменяет его функцией apply и сохраняет вместе
+// с событием update в outbox в одной транзакции. Блокировка (SELECT ... FOR UPDATE) держится до фиксации,
+// поэтому параллельные частичные изменения одного объявления применяются по очереди и не затирают друг друга.
+// Ошибка apply возвращается без изменений; если объявления нет, возвращается ошибка, оборачивающая sql.ErrNoRows.
+func (repo *AdvertisementRepository) Patch(id int, apply func(advertisement *model.Advertisement) error) (*model.Advertisement, error) {
+	var advertisement model.Advertisement
+	var applyErr error
+
+	err := repo.inTransaction(func(tx *sqlx.Tx) error {
+		query := `
+			SELECT ` + advertisementColumns + `
+			FROM advertisements
+			WHERE id = $1 AND ` + notDeleted + `
+			FOR UPDATE
+		`
+		if err := tx.Get(&advertisement, query, id); err != nil {
+			return err
+		}
+
+		if applyErr = apply(&advertisement); applyErr != nil {
+			return applyErr
+		}
+
+		return updateAdvertisement(tx, &advertisement)
+	})
+	if applyErr != nil {
+		return nil, applyErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ошибка изменения объявления %d в БД: %w", id, err)
+	}
+
+	return &advertisement, nil
+}
+
+// updateAdvertisement перезаписывает строку объявления и добавляет событие update в outbox внутри tx
+func updateAdvertisement(tx *sqlx.Tx, advertisement *model.Advertisement) error {
+	query := `
+		UPDATE advertisements SET
+			product_name = :product_name, description = :description, brand = :brand, category = :category,
+			price = :price, currency = :currency, stock = :stock, ean = :ean, color = :color, size = :size,
+			availability = :availability
+		WHERE id = :id AND ` + notDeleted + `
+		RETURNING id
+	`
+
+	statement, err := tx.PrepareNamed(query)
+	if err != nil {
+		return err
+	}
+	defer statement.Close()
+
+	var id int
+	if err := statement.Get(&id, advertisement); err != nil {
+		return err
+	}
+
+	return WriteOutboxEvents(tx, []model.AdvertisementEvent{
+		{Type: model.AdvertisementUpdated, ID: advertisement.Index, Advertisement: advertisement},
+	})
+}
+
+// Delete мягко удаляет объявление (выставляет deleted_at) и в той же транзакции записывает событие delete в outbox.
+// Строка остаётся в таблице, чтобы синхронизация по updated_at увидела удаление.
+// Если объявления нет или оно уже удалено, возвращается ошибка, оборачивающая sql.ErrNoRows.
+func (repo *AdvertisementRepository) Delete(id int) error {
+	err := repo.inTransaction(func(tx *sqlx.Tx) error {
+		query := `UPDATE advertisements SET deleted_at = now() WHERE id = $1 AND ` + notDeleted + ` RETURNING id`
+
+		var deletedID int
+		if err := tx.Get(&deletedID, query, id); err != nil {
+			return err
+		}
+
+		return WriteOutboxEvents(tx, []model.AdvertisementEvent{{Type: model.AdvertisementDeleted, ID: id}})
+	})
+	if err != nil {
+		return fmt.Errorf("ошибка удаления объявления %d из БД: %w", id, err)
+	}
+
+	return nil
+}
+
+// inTransaction выполняет action в транзакции и фиксирует её, если action не вернул ошибку
+func (repo *AdvertisementRepository) inTransaction(action func(tx *sqlx.Tx) error) error {
+	tx, err := repo.Database.DB.Beginx()
+	if err != nil {
+		return fmt.Errorf("ошибка начала транзакции: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := action(tx); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
 func (repo *AdvertisementRepository) GetAdvertisementsBatch(limit int, offset int) ([]model.Advertisement, error) {
 	query := `
-		SELECT id, product_name, brand, category, price, stock, availability
+		SELECT ` + advertisementColumns + `
 		FROM advertisements 
+		WHERE ` + notDeleted + `
 		ORDER BY id 
 		LIMIT $1 OFFSET $2
 	`
@@ -49,11 +185,31 @@ func (repo *AdvertisementRepository) GetAdvertisementsBatch(limit int, offset in
 	return advertisements, nil
 }
 
+// GetAdvertisementsAfter загружает следующую страницу объявлений по ключу (keyset pagination):
+// выборка начинается сразу после lastID и использует индекс первичного ключа вместо OFFSET
+func (repo *AdvertisementRepository) GetAdvertisementsAfter(lastID int, limit int) ([]model.Advertisement, error) {
+	query := `
+		SELECT ` + advertisementColumns + `
+		FROM advertisements 
+		WHERE id > $1 AND ` + notDeleted + `
+		ORDER BY id 
+		LIMIT $2
+	`
+
+	var advertisements []model.Advertisement
+	err := repo.Database.DB.Select(&advertisements, query, lastID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка загрузки объявлений из БД: %w", err)
+	}
+
+	return advertisements, nil
+}
+
 func (repo *AdvertisementRepository) GetAdvertisementById(id int) (model.Advertisement, error) {
 	query := `
-		SELECT id, product_name, brand, category, price, stock, availability
+		SELECT ` + advertisementColumns + `
 		FROM advertisements 
-		WHERE id = $1
+		WHERE id = $1 AND ` + notDeleted + `
 		`
 
 	var advertisement model.Advertisement
@@ -64,3 +220,35 @@ func (repo *AdvertisementRepository) GetAdvertisementById(id int) (model.Adverti
 
 	return advertisement, nil
 }
+
+// GetAdvertisementsChangedSince загружает следующую страницу изменённых объявлений по ключу (updated_at, id).
+// Мягко удалённые объявления тоже возвращаются — с заполненным DeletedAt, чтобы удаление дошло до индекса.
+// Граница отставания считается по часам БД: updated_at выставляется той же now(), поэтому расхождение
+// часов приложения и БД не сдвигает её.
+func (repo *AdvertisementRepository) GetAdvertisementsChangedSince(after model.SyncWatermark, lag time.Duration, limit int) ([]model.AdvertisementChange, error) {
+	query := `
+		SELECT ` + advertisementColumns + `, updated_at, deleted_at
+		FROM advertisements 
+		WHERE (updated_at, id) > ($1, $2) AND updated_at <= now() - $3 * interval '1 microsecond'
+		ORDER BY updated_at, id 
+		LIMIT $4
+	`
+
+	var changes []model.AdvertisementChange
+	err := repo.Database.DB.Select(&changes, query, after.UpdatedAt, after.ID, lag.Microseconds(), limit)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка загрузки изменённых объявлений из БД: %w", err)
+	}
+
+	return changes, nil
+}
+
+// GetDatabaseTime возвращает now() БД — ту же отметку времени, что попадает в updated_at
+func (repo *AdvertisementRepository) GetDatabaseTime() (time.Time, error) {
+	var now time.Time
+	if err := repo.Database.DB.Get(&now, "SELECT now()"); err != nil {
+		return time.Time{}, fmt.Errorf("ошибка получения времени БД: %w", err)
+	}
+
+	return now, nil
+}
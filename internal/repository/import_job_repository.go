@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"SearchService/internal"
+	"SearchService/internal/model"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+)
+
+// importJobColumns — все колонки таблицы import_jobs, соответствующие полям model.ImportJob
+const importJobColumns = `id, status, file_name, file_path, batch_size, rows_processed, rows_failed, error_samples, error,
+	created_at, started_at, finished_at`
+
+// ImportJobRepository хранит задания импорта CSV в таблице import_jobs
+type ImportJobRepository struct {
+	Database *internal.Database
+}
+
+func NewImportJobRepository(database *internal.Database) *ImportJobRepository {
+	return &ImportJobRepository{Database: database}
+}
+
+func (repo *ImportJobRepository) CreateImportJob(job *model.ImportJob) error {
+	query := `
+		INSERT INTO import_jobs (status, file_name, file_path, batch_size)
+		VALUES ($1, $2, $3, $4)
+		RETURNING ` + importJobColumns
+
+	if err := repo.Database.DB.Get(job, query, model.ImportQueued, job.FileName, job.FilePath, job.BatchSize); err != nil {
+		return fmt.Errorf("ошибка создания задания импорта: %w", err)
+	}
+
+	return nil
+}
+
+// GetImportJob возвращает задание по id. Если задания нет, возвращается ошибка, оборачивающая sql.ErrNoRows.
+func (repo *ImportJobRepository) GetImportJob(id int64) (model.ImportJob, error) {
+	var job model.ImportJob
+	if err := repo.Database.DB.Get(&job, `SELECT `+importJobColumns+` FROM import_jobs WHERE id = $1`, id); err != nil {
+		return model.ImportJob{}, fmt.Errorf("ошибка загрузки задания импорта %d: %w", id, err)
+	}
+
+	return job, nil
+}
+
+// ClaimNextImportJob переводит самое старое задание из очереди в running.
+// Задания выполняет единственный imports.Runner сервиса, поэтому конкурентных захватов нет.
+func (repo *ImportJobRepository) ClaimNextImportJob() (model.ImportJob, bool, error) {
+	query := `
+		UPDATE import_jobs SET status = $1, started_at = now()
+		WHERE id = (
+			SELECT id FROM import_jobs
+			WHERE status = $2
+			ORDER BY id
+			LIMIT 1
+		)
+		RETURNING ` + importJobColumns
+
+	var job model.ImportJob
+	err := repo.Database.DB.Get(&job, query, model.ImportRunning, model.ImportQueued)
+	if errors.Is(err, sql.ErrNoRows) {
+		return model.ImportJob{}, false, nil
+	}
+	if err != nil {
+		return model.ImportJob{}, false, fmt.Errorf("ошибка получения задания импорта из очереди: %w", err)
+	}
+
+	return job, true, nil
+}
+
+func (repo *ImportJobRepository) SaveImportProgress(id int64, rowsProcessed int, rowsFailed int, errorSamples []string) error {
+	query := `UPDATE import_jobs SET rows_processed = $2, rows_failed = $3, error_samples = $4 WHERE id = $1`
+
+	if _, err := repo.Database.DB.Exec(query, id, rowsProcessed, rowsFailed, pq.Array(errorSamples)); err != nil {
+		return fmt.Errorf("ошибка сохранения прогресса задания импорта %d: %w", id, err)
+	}
+
+	return nil
+}
+
+func (repo *ImportJobRepository) FinishImportJob(id int64, status model.ImportJobStatus, message string) error {
+	query := `UPDATE import_jobs SET status = $2, error = $3, finished_at = now() WHERE id = $1`
+
+	if _, err := repo.Database.DB.Exec(query, id, status, message); err != nil {
+		return fmt.Errorf("ошибка завершения задания импорта %d: %w", id, err)
+	}
+
+	return nil
+}
+
+func (repo *ImportJobRepository) CancelQueuedImportJob(id int64) (bool, error) {
+	query := `UPDATE import_jobs SET status = $2, finished_at = now() WHERE id = $1 AND status = $3`
+
+	result, err := repo.Database.DB.Exec(query, id, model.ImportCancelled, model.ImportQueued)
+	if err != nil {
+		return false, fmt.Errorf("ошибка отмены задания импорта %d: %w", id, err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("ошибка отмены задания импорта %d: %w", id, err)
+	}
+
+	return affected > 0, nil
+}
+
+func (repo *ImportJobRepository) FailInterruptedImportJobs(message string) (int, error) {
+	query := `UPDATE import_jobs SET status = $1, error = $2, finished_at = now() WHERE status = $3`
+
+	result, err := repo.Database.DB.Exec(query, model.ImportFailed, message, model.ImportRunning)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка завершения прерванных заданий импорта: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("ошибка завершения прерванных заданий импорта: %w", err)
+	}
+
+	return int(affected), nil
+}
@@ -0,0 +1,121 @@
+package repository
+
+// outbox_repository содержит транзакционный outbox: событие об изменении объявления записывается
+// в таблицу advertisement_outbox в той же транзакции, что и само изменение, а relay публикует
+// неотправленные записи в Kafka в порядке id. Так изменение не может попасть в БД без события.
+// Схема таблицы — internal/migrations/000002_create_advertisement_outbox.up.sql.
+
+import (
+	"SearchService/internal"
+	"SearchService/internal/model"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"strings"
+	"time"
+)
+
+// OutboxRecord — неотправленное событие из advertisement_outbox
+type OutboxRecord struct {
+	ID              int64     `db:"id"`
+	AdvertisementID int       `db:"advertisement_id"`
+	EventType       string    `db:"event_type"`
+	Payload         []byte    `db:"payload"`
+	CreatedAt       time.Time `db:"created_at"`
+}
+
+// WriteOutboxEvents добавляет события в outbox внутри транзакции tx.
+// Вызывается в той же транзакции, в которой изменяются сами объявления.
+func WriteOutboxEvents(tx *sqlx.Tx, events []model.AdvertisementEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	var placeholders []string
+	var args []interface{}
+	for index, event := range events {
+		payload, err := json.Marshal(event)
+		if err != nil {
+			return fmt.Errorf("ошибка сериализации события объявления %d: %w", event.ID, err)
+		}
+
+		offset := index * 3
+		placeholders = append(placeholders, fmt.Sprintf("($%d, $%d, $%d)", offset+1, offset+2, offset+3))
+		args = append(args, event.ID, string(event.Type), string(payload))
+	}
+
+	query := `INSERT INTO advertisement_outbox (advertisement_id, event_type, payload) VALUES ` + strings.Join(placeholders, ",")
+	if _, err := tx.Exec(query, args...); err != nil {
+		return fmt.Errorf("ошибка записи событий в outbox: %w", err)
+	}
+
+	return nil
+}
+
+type OutboxRepository struct {
+	Database *internal.Database
+}
+
+func NewOutboxRepository(database *internal.Database) *OutboxRepository {
+	return &OutboxRepository{Database: database}
+}
+
+// ProcessUnsent блокирует до limit самых старых неотправленных записей, передаёт их в publish
+// и отмечает отправленными те, что publish подтвердил. publish возвращает количество записей
+// с начала списка, которые удалось опубликовать, — остальные останутся в outbox в прежнем порядке.
+// Блокировка строк не даёт второму relay опубликовать те же записи параллельно.
+func (repo *OutboxRepository) ProcessUnsent(limit int, publish func([]OutboxRecord) (int, error)) (int, error) {
+	tx, err := repo.Database.DB.Beginx()
+	if err != nil {
+		return 0, fmt.Errorf("ошибка начала транзакции: %w", err)
+	}
+	defer tx.Rollback()
+
+	return processUnsent(tx, limit, publish)
+}
+
+// outboxTx — операции транзакции, которые нужны ProcessUnsent; реализуется *sqlx.Tx
+type outboxTx interface {
+	Select(dest interface{}, query string, args ...interface{}) error
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Commit() error
+}
+
+// processUnsent выполняет ProcessUnsent внутри открытой транзакции tx. Если publish не подтвердил
+// ни одной записи, транзакция не фиксируется, и блокировки снимаются откатом у вызывающего.
+func processUnsent(tx outboxTx, limit int, publish func([]OutboxRecord) (int, error)) (int, error) {
+	var records []OutboxRecord
+	query := `
+		SELECT id, advertisement_id, event_type, payload, created_at
+		FROM advertisement_outbox
+		WHERE sent_at IS NULL
+		ORDER BY id
+		LIMIT $1
+		FOR UPDATE
+	`
+	if err := tx.Select(&records, query, limit); err != nil {
+		return 0, fmt.Errorf("ошибка загрузки событий из outbox: %w", err)
+	}
+	if len(records) == 0 {
+		return 0, nil
+	}
+
+	published, publishErr := publish(records)
+	if published > 0 {
+		ids := make([]int64, 0, published)
+		for _, record := range records[:published] {
+			ids = append(ids, record.ID)
+		}
+
+		if _, err := tx.Exec(`UPDATE advertisement_outbox SET sent_at = now() WHERE id = ANY($1)`, pq.Array(ids)); err != nil {
+			return 0, fmt.Errorf("ошибка отметки отправленных событий: %w", err)
+		}
+		if err := tx.Commit(); err != nil {
+			return 0, fmt.Errorf("ошибка фиксации транзакции: %w", err)
+		}
+	}
+
+	return published, publishErr
+}
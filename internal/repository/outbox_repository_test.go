@@ -0,0 +1,150 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/lib/pq"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// memoryOutboxTx — outboxTx поверх записей в памяти. Отметки sent_at применяются только при Commit.
+type memoryOutboxTx struct {
+	records   []OutboxRecord
+	sent      map[int64]bool
+	pending   []int64
+	committed bool
+}
+
+func newMemoryOutboxTx(ids []int64, sent ...int64) *memoryOutboxTx {
+	tx := &memoryOutboxTx{sent: make(map[int64]bool)}
+	for _, id := range ids {
+		tx.records = append(tx.records, OutboxRecord{ID: id, AdvertisementID: int(id) * 10})
+	}
+	for _, id := range sent {
+		tx.sent[id] = true
+	}
+	return tx
+}
+
+func (tx *memoryOutboxTx) Select(dest interface{}, _ string, args ...interface{}) error {
+	limit := args[0].(int)
+	records := dest.(*[]OutboxRecord)
+
+	sorted := append([]OutboxRecord(nil), tx.records...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].ID < sorted[j].ID })
+	for _, record := range sorted {
+		if !tx.sent[record.ID] && len(*records) < limit {
+			*records = append(*records, record)
+		}
+	}
+	return nil
+}
+
+func (tx *memoryOutboxTx) Exec(_ string, args ...interface{}) (sql.Result, error) {
+	ids := args[0].(*pq.Int64Array)
+	tx.pending = append(tx.pending, *ids...)
+	return nil, nil
+}
+
+func (tx *memoryOutboxTx) Commit() error {
+	for _, id := range tx.pending {
+		tx.sent[id] = true
+	}
+	tx.committed = true
+	return nil
+}
+
+func (tx *memoryOutboxTx) unsent() []int64 {
+	var ids []int64
+	for _, record := range tx.records {
+		if !tx.sent[record.ID] {
+			ids = append(ids, record.ID)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+func TestProcessUnsent(t *testing.T) {
+	publishErr := errors.New("broker unavailable")
+
+	tests := []struct {
+		name          string
+		published     int
+		publishErr    error
+		wantClaimed   []int64
+		wantUnsent    []int64
+		wantCommitted bool
+	}{
+		{
+			name:          "all published",
+			published:     2,
+			wantClaimed:   []int64{2, 3},
+			wantUnsent:    []int64{4},
+			wantCommitted: true,
+		},
+		{
+			// Подтверждённый префикс отмечается, хвост остаётся для следующего прохода
+			name:          "partially published",
+			published:     1,
+			publishErr:    publishErr,
+			wantClaimed:   []int64{2, 3},
+			wantUnsent:    []int64{3, 4},
+			wantCommitted: true,
+		},
+		{
+			name:        "publish failed",
+			publishErr:  publishErr,
+			wantClaimed: []int64{2, 3},
+			wantUnsent:  []int64{2, 3, 4},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx := newMemoryOutboxTx([]int64{4, 1, 3, 2}, 1)
+
+			var claimed []int64
+			published, err := processUnsent(tx, 2, func(records []OutboxRecord) (int, error) {
+				for _, record := range records {
+					claimed = append(claimed, record.ID)
+				}
+				// Пока publish не вернулся, ни одна запись не должна быть отмечена отправленной
+				if len(tx.pending) != 0 || tx.committed {
+					t.Error("records marked sent before publish returned")
+				}
+				return tt.published, tt.publishErr
+			})
+
+			if !errors.Is(err, tt.publishErr) {
+				t.Errorf("processUnsent() error = %v, want %v", err, tt.publishErr)
+			}
+			if published != tt.published {
+				t.Errorf("processUnsent() = %d, want %d", published, tt.published)
+			}
+			if !reflect.DeepEqual(claimed, tt.wantClaimed) {
+				t.Errorf("claimed records = %v, want %v", claimed, tt.wantClaimed)
+			}
+			if got := tx.unsent(); !reflect.DeepEqual(got, tt.wantUnsent) {
+				t.Errorf("unsent records = %v, want %v", got, tt.wantUnsent)
+			}
+			if tx.committed != tt.wantCommitted {
+				t.Errorf("committed = %v, want %v", tx.committed, tt.wantCommitted)
+			}
+		})
+	}
+
+	t.Run("nothing to publish", func(t *testing.T) {
+		tx := newMemoryOutboxTx([]int64{1}, 1)
+
+		published, err := processUnsent(tx, 10, func([]OutboxRecord) (int, error) {
+			t.Error("publish called without records")
+			return 0, nil
+		})
+		if published != 0 || err != nil {
+			t.Errorf("processUnsent() = %d, %v, want 0, nil", published, err)
+		}
+	})
+}
@@ -0,0 +1,281 @@
+package repository
+
+// search_aggregations содержит построение и разбор агрегаций поискового запроса (фасеты)
+
+import (
+	"SearchService/internal/model"
+	"encoding/json"
+	"fmt"
+	"math"
+)
+
+// facetBucketsSize — максимальное количество значений, возвращаемых для одного фасета
+const facetBucketsSize = 20
+
+// facetField возвращает keyword-поле, по которому считается и фильтруется фасет.
+// Бренд и категория — текстовые поля с подполем normalized (lowercase_normalizer), поэтому
+// "Braun" и "braun" считаются одним значением; остальные фасеты сами являются keyword.
+func facetField(facet model.Facet) string {
+	switch facet {
+	case model.FacetBrand, model.FacetCategory:
+		return string(facet) + ".normalized"
+	default:
+		return string(facet)
+	}
+}
+
+// facetDisplayField возвращает поле с исходным написанием значения фасета или "", если
+// facetField и так хранит значение без изменений. Нормализованное значение приведено к нижнему
+// регистру, поэтому пользователю показывается самое частое исходное написание.
+func facetDisplayField(facet model.Facet) string {
+	switch facet {
+	case model.FacetBrand, model.FacetCategory:
+		return string(facet) + ".keyword"
+	default:
+		return ""
+	}
+}
+
+// buildSelectionFilter строит term-фильтры по выбранным значениям фасетов, пропуская фасет exclude.
+// Пропуск нужен для агрегации самого фасета: выбор бренда не должен скрывать остальные бренды.
+func buildSelectionFilter(selections map[model.Facet][]string, exclude model.Facet) []map[string]interface{} {
+	filter := make([]map[string]interface{}, 0, len(selections))
+
+	for _, facet := range model.Facets {
+		values := selections[facet]
+		if facet == exclude || len(values) == 0 {
+			continue
+		}
+
+		filter = append(filter, map[string]interface{}{
+			"terms": map[string]interface{}{
+				facetField(facet): values,
+			},
+		})
+	}
+
+	return filter
+}
+
+// buildPostFilter возвращает post_filter по выбранным фасетам или nil, если ничего не выбрано
+func buildPostFilter(selections map[model.Facet][]string) map[string]interface{} {
+	filter := buildSelectionFilter(selections, "")
+	if len(filter) == 0 {
+		return nil
+	}
+
+	return map[string]interface{}{
+		"bool": map[string]interface{}{
+			"filter": filter,
+		},
+	}
+}
+
+// buildFacetAggregations строит по одной агрегации на фасет.
+// Каждая агрегация учитывает выбор во всех фасетах, кроме своего собственного.
+func buildFacetAggregations(selections map[model.Facet][]string) map[string]interface{} {
+	aggs := make(map[string]interface{}, len(model.Facets))
+
+	for _, facet := range model.Facets {
+		values := map[string]interface{}{
+			"terms": map[string]interface{}{
+				"field": facetField(facet),
+				"size":  facetBucketsSize,
+			},
+		}
+		if displayField := facetDisplayField(facet); displayField != "" {
+			values["aggs"] = map[string]interface{}{
+				"display": map[string]interface{}{
+					"terms": map[string]interface{}{
+						"field": displayField,
+						"size":  1,
+					},
+				},
+			}
+		}
+
+		aggs[string(facet)] = map[string]interface{}{
+			"filter": map[string]interface{}{
+				"bool": map[string]interface{}{
+					"filter": buildSelectionFilter(selections, facet),
+				},
+			},
+			"aggs": map[string]interface{}{
+				"values": values,
+			},
+		}
+	}
+
+	return aggs
+}
+
+// parseFacets извлекает значения фасетов из секции aggregations ответа Elasticsearch
+func parseFacets(aggregations map[string]json.RawMessage) (map[model.Facet][]model.FacetBucket, error) {
+	facets := make(map[model.Facet][]model.FacetBucket, len(model.Facets))
+
+	for _, facet := range model.Facets {
+		raw, ok := aggregations[string(facet)]
+		if !ok {
+			continue
+		}
+
+		var aggregation struct {
+			Values struct {
+				Buckets []struct {
+					Key      string `json:"key"`
+					DocCount int64  `json:"doc_count"`
+					Display  struct {
+						Buckets []struct {
+							Key string `json:"key"`
+						} `json:"buckets"`
+					} `json:"display"`
+				} `json:"buckets"`
+			} `json:"values"`
+		}
+		if err := json.Unmarshal(raw, &aggregation); err != nil {
+			return nil, fmt.Errorf("ошибка парсинга фасета %s: %w", facet, err)
+		}
+
+		buckets := make([]model.FacetBucket, 0, len(aggregation.Values.Buckets))
+		for _, bucket := range aggregation.Values.Buckets {
+			value := bucket.Key
+			if len(bucket.Display.Buckets) > 0 {
+				value = bucket.Display.Buckets[0].Key
+			}
+			buckets = append(buckets, model.FacetBucket{Value: value, Count: bucket.DocCount})
+		}
+		facets[facet] = buckets
+	}
+
+	return facets, nil
+}
+
+// priceAggregationName — имя агрегации-обёртки для статистики и распределения цен
+const priceAggregationName = "price"
+
+// buildPriceAggregations строит агрегации по цене. Они считаются по той же выдаче, что видит
+// пользователь, поэтому учитывают выбор во всех фасетах (post_filter на агрегации не действует).
+func buildPriceAggregations(options model.PriceAggregationOptions, selections map[model.Facet][]string) map[string]interface{} {
+	subAggs := make(map[string]interface{})
+
+	if options.WithStats {
+		subAggs["stats"] = map[string]interface{}{
+			"stats": map[string]interface{}{"field": "price"},
+		}
+	}
+
+	if options.HistogramInterval > 0 {
+		// Пустые корзины не создаются: иначе их число определяется разбросом цен, а не найденными объявлениями
+		subAggs["histogram"] = map[string]interface{}{
+			"histogram": map[string]interface{}{
+				"field":         "price",
+				"interval":      options.HistogramInterval,
+				"min_doc_count": 1,
+			},
+		}
+	}
+
+	if len(options.Ranges) > 0 {
+		subAggs["ranges"] = map[string]interface{}{
+			"range": map[string]interface{}{
+				"field":  "price",
+				"ranges": options.Ranges,
+			},
+		}
+	}
+
+	return map[string]interface{}{
+		"filter": map[string]interface{}{
+			"bool": map[string]interface{}{
+				"filter": buildSelectionFilter(selections, ""),
+			},
+		},
+		"aggs": subAggs,
+	}
+}
+
+// parsePriceAggregations разбирает результат агрегации, построенной buildPriceAggregations
+func parsePriceAggregations(raw json.RawMessage, options model.PriceAggregationOptions) (*model.PriceAggregationResult, error) {
+	type bucket struct {
+		Key      float64  `json:"key"`
+		From     *float64 `json:"from"`
+		To       *float64 `json:"to"`
+		DocCount int64    `json:"doc_count"`
+	}
+
+	var aggregation struct {
+		Stats struct {
+			Count int64    `json:"count"`
+			Min   *float64 `json:"min"`
+			Max   *float64 `json:"max"`
+			Avg   *float64 `json:"avg"`
+		} `json:"stats"`
+		Histogram struct {
+			Buckets []bucket `json:"buckets"`
+		} `json:"histogram"`
+		Ranges struct {
+			Buckets []bucket `json:"buckets"`
+		} `json:"ranges"`
+	}
+	if err := json.Unmarshal(raw, &aggregation); err != nil {
+		return nil, fmt.Errorf("ошибка парсинга агрегаций по цене: %w", err)
+	}
+
+	result := &model.PriceAggregationResult{HistogramInterval: options.HistogramInterval}
+
+	if options.WithStats {
+		result.Stats = &model.PriceStats{
+			Count: aggregation.Stats.Count,
+			Min:   aggregation.Stats.Min,
+			Max:   aggregation.Stats.Max,
+			Avg:   aggregation.Stats.Avg,
+		}
+	}
+
+	for _, histogramBucket := range aggregation.Histogram.Buckets {
+		from := histogramBucket.Key
+		to := histogramBucket.Key + options.HistogramInterval
+		result.Histogram = append(result.Histogram, model.PriceBucket{
+			PriceRange: model.PriceRange{From: &from, To: &to},
+			Count:      histogramBucket.DocCount,
+		})
+	}
+
+	for _, rangeBucket := range aggregation.Ranges.Buckets {
+		result.Ranges = append(result.Ranges, model.PriceBucket{
+			PriceRange: model.PriceRange{From: rangeBucket.From, To: rangeBucket.To},
+			Count:      rangeBucket.DocCount,
+		})
+	}
+
+	return result, nil
+}
+
+// buildPriceSpanQuery строит запрос границ цен по той же выдаче, по которой считается гистограмма
+func buildPriceSpanQuery(filters model.SearchFilters, fieldBoosts FieldBoosts) map[string]interface{} {
+	filters.WithFacets = false
+	filters.WithDidYouMean = false
+	filters.PriceAggregations = model.PriceAggregationOptions{WithStats: true}
+
+	query := buildSearchQuery(filters, model.Pagination{}, fieldBoosts)
+	delete(query, "sort")
+	delete(query, "post_filter")
+
+	return query
+}
+
+// histogramInterval возвращает шаг гистограммы не меньше requested, при котором цены из [min, max]
+// укладываются в model.MaxPriceHistogramBuckets корзин. Пустая выдача (min или max равны nil) шаг не меняет.
+func histogramInterval(requested float64, min *float64, max *float64) float64 {
+	if min == nil || max == nil {
+		return requested
+	}
+
+	// Корзин с ценами из [min, max] не больше (max-min)/interval + 1
+	span := *max - *min
+	if span/requested+1 <= model.MaxPriceHistogramBuckets {
+		return requested
+	}
+
+	return math.Max(requested, math.Ceil(span/(model.MaxPriceHistogramBuckets-1)))
+}
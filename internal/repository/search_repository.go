@@ -7,49 +7,301 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/elastic/go-elasticsearch/v8"
+	"sort"
+	"strconv"
+	"strings"
 )
 
 type SearchRepository struct {
-	client *elasticsearch.Client
-	index  string
+	client      *elasticsearch.Client
+	index       string
+	fieldBoosts FieldBoosts
 }
 
-func NewElasticRepository(client *elasticsearch.Client, index string) *SearchRepository {
+// FieldBoosts — веса полей для полнотекстового поиска по параметру q: поле индекса -> boost
+type FieldBoosts map[string]float64
+
+// DefaultFieldBoosts используются, если веса не заданы в конфигурации
+var DefaultFieldBoosts = FieldBoosts{
+	"product_name": 4,
+	"brand":        2,
+	"category":     1.5,
+	"description":  1,
+}
+
+// searchableFields — поля, по которым разрешён полнотекстовый поиск
+var searchableFields = map[string]bool{
+	"product_name": true,
+	"brand":        true,
+	"category":     true,
+	"description":  true,
+}
+
+// languageSubfields — подполя с языковыми анализаторами (english, russian) для текстовых полей.
+// Поиск по такому полю идёт одновременно по основному полю и по его языковым подполям.
+var languageSubfields = map[string][]string{
+	"product_name": {"en", "ru"},
+	"description":  {"en", "ru"},
+}
+
+// analyzedFields возвращает основное поле и его языковые подполя
+func analyzedFields(field string) []string {
+	fields := []string{field}
+	for _, subfield := range languageSubfields[field] {
+		fields = append(fields, field+"."+subfield)
+	}
+	return fields
+}
+
+// ParseFieldBoosts разбирает веса полей из строки вида "product_name:4,brand:2,description:1"
+func ParseFieldBoosts(raw string) (FieldBoosts, error) {
+	boosts := make(FieldBoosts)
+
+	for _, pair := range strings.Split(raw, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		field, rawBoost, found := strings.Cut(pair, ":")
+		if !found {
+			return nil, fmt.Errorf("неверный формат веса поля: %q", pair)
+		}
+		field = strings.TrimSpace(field)
+		if !searchableFields[field] {
+			return nil, fmt.Errorf("поле %q не поддерживает полнотекстовый поиск", field)
+		}
+
+		boost, err := strconv.ParseFloat(strings.TrimSpace(rawBoost), 64)
+		if err != nil || boost <= 0 {
+			return nil, fmt.Errorf("вес поля %s должен быть числом > 0", field)
+		}
+		boosts[field] = boost
+	}
+
+	if len(boosts) == 0 {
+		return nil, fmt.Errorf("не задано ни одного веса поля")
+	}
+
+	return boosts, nil
+}
+
+// multiMatchFields возвращает поля в формате multi_match ("product_name^4") в стабильном порядке
+func (boosts FieldBoosts) multiMatchFields() []string {
+	fields := make([]string, 0, len(boosts))
+	for field, boost := range boosts {
+		for _, analyzedField := range analyzedFields(field) {
+			fields = append(fields, analyzedField+"^"+strconv.FormatFloat(boost, 'f', -1, 64))
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// NewElasticRepository создаёт репозиторий поиска. Если fieldBoosts пуст, используются DefaultFieldBoosts.
+func NewElasticRepository(client *elasticsearch.Client, index string, fieldBoosts FieldBoosts) *SearchRepository {
+	if len(fieldBoosts) == 0 {
+		fieldBoosts = DefaultFieldBoosts
+	}
+
 	return &SearchRepository{
-		client: client,
-		index:  index,
+		client:      client,
+		index:       index,
+		fieldBoosts: fieldBoosts,
+	}
+}
+
+func (repo *SearchRepository) SearchAdvertisements(ctx context.Context, filters model.SearchFilters, pagination model.Pagination) (model.SearchResult, error) {
+	if filters.PriceAggregations.HistogramInterval > 0 {
+		min, max, err := repo.priceSpan(ctx, filters)
+		if err != nil {
+			return model.SearchResult{}, err
+		}
+		filters.PriceAggregations.HistogramInterval = histogramInterval(filters.PriceAggregations.HistogramInterval, min, max)
+	}
+
+	query := buildSearchQuery(filters, pagination, repo.fieldBoosts)
+
+	body, err := json.Marshal(query)
+	if err != nil {
+		return model.SearchResult{}, fmt.Errorf("ошибка сериализации запроса: %w", err)
+	}
+
+	// Выполнение запроса
+	res, err := repo.client.Search(
+		repo.client.Search.WithContext(ctx),
+		repo.client.Search.WithIndex(repo.index),
+		repo.client.Search.WithBody(bytes.NewReader(body)),
+		repo.client.Search.WithTrackTotalHits(true),
+	)
+	if err != nil {
+		return model.SearchResult{}, fmt.Errorf("ошибка поиска: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return model.SearchResult{}, fmt.Errorf("ошибка ответа от ElasticSearch: %s", res.String())
+	}
+
+	// Парсим результат
+	var result struct {
+		Hits struct {
+			Total struct {
+				Value int64 `json:"value"`
+			} `json:"total"`
+			Hits []struct {
+				Source model.Advertisement `json:"_source"`
+			} `json:"hits"`
+		} `json:"hits"`
+		Aggregations map[string]json.RawMessage    `json:"aggregations"`
+		Suggest      map[string][]termSuggestEntry `json:"suggest"`
+	}
+
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return model.SearchResult{}, fmt.Errorf("ошибка парсинга ответа: %w", err)
+	}
+
+	ads := make([]model.Advertisement, 0, len(result.Hits.Hits))
+	for _, hit := range result.Hits.Hits {
+		ads = append(ads, hit.Source)
+	}
+
+	searchResult := model.SearchResult{
+		Items:    ads,
+		Total:    result.Hits.Total.Value,
+		Page:     pagination.Page(),
+		PageSize: pagination.Size,
+		HasMore:  int64(pagination.From+len(ads)) < result.Hits.Total.Value,
+	}
+
+	if filters.WithFacets {
+		searchResult.Facets, err = parseFacets(result.Aggregations)
+		if err != nil {
+			return model.SearchResult{}, err
+		}
+	}
+
+	if filters.PriceAggregations.Enabled() {
+		searchResult.Price, err = parsePriceAggregations(result.Aggregations[priceAggregationName], filters.PriceAggregations)
+		if err != nil {
+			return model.SearchResult{}, err
+		}
+	}
+
+	if filters.WithDidYouMean && searchResult.Total <= model.DidYouMeanMaxHits {
+		searchResult.DidYouMean = parseDidYouMean(result.Suggest)
+	}
+
+	return searchResult, nil
+}
+
+// priceSpan возвращает минимальную и максимальную цену в выдаче по filters; для пустой выдачи — nil
+func (repo *SearchRepository) priceSpan(ctx context.Context, filters model.SearchFilters) (min *float64, max *float64, err error) {
+	body, err := json.Marshal(buildPriceSpanQuery(filters, repo.fieldBoosts))
+	if err != nil {
+		return nil, nil, fmt.Errorf("ошибка сериализации запроса: %w", err)
+	}
+
+	res, err := repo.client.Search(
+		repo.client.Search.WithContext(ctx),
+		repo.client.Search.WithIndex(repo.index),
+		repo.client.Search.WithBody(bytes.NewReader(body)),
+	)
+	if err != nil {
+		return nil, nil, fmt.Errorf("ошибка получения разброса цен: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return nil, nil, fmt.Errorf("ошибка ответа от ElasticSearch: %s", res.String())
+	}
+
+	var result struct {
+		Aggregations map[string]json.RawMessage `json:"aggregations"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return nil, nil, fmt.Errorf("ошибка парсинга ответа: %w", err)
+	}
+
+	span, err := parsePriceAggregations(result.Aggregations[priceAggregationName], model.PriceAggregationOptions{WithStats: true})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return span.Stats.Min, span.Stats.Max, nil
+}
+
+// buildMatchQuery строит запрос по текстовому полю, с нечёткостью, если она задана.
+// Для полей с языковыми подполями используется multi_match по всем вариантам анализа.
+func buildMatchQuery(field string, value string, fuzziness string) map[string]interface{} {
+	fields := analyzedFields(field)
+	if len(fields) > 1 {
+		multiMatch := map[string]interface{}{
+			"query":  value,
+			"fields": fields,
+			"type":   "best_fields",
+		}
+		if fuzziness != "" {
+			multiMatch["fuzziness"] = fuzziness
+		}
+
+		return map[string]interface{}{
+			"multi_match": multiMatch,
+		}
+	}
+
+	if fuzziness == "" {
+		return map[string]interface{}{
+			"match": map[string]interface{}{
+				field: value,
+			},
+		}
+	}
+
+	return map[string]interface{}{
+		"match": map[string]interface{}{
+			field: map[string]interface{}{
+				"query":     value,
+				"fuzziness": fuzziness,
+			},
+		},
 	}
 }
 
-func (repo *SearchRepository) SearchAdvertisements(ctx context.Context, filters model.SearchFilters) ([]model.Advertisement, error) {
+// buildSearchQuery собирает тело запроса к Elasticsearch по фильтрам и окну пагинации
+func buildSearchQuery(filters model.SearchFilters, pagination model.Pagination, fieldBoosts FieldBoosts) map[string]interface{} {
 	var must []map[string]interface{}
 	var filter []map[string]interface{}
 
-	// Match по названию
-	if filters.ProductName != "" {
+	// Свободный текст по нескольким полям с весами
+	if filters.Query != "" {
+		multiMatch := map[string]interface{}{
+			"query":  filters.Query,
+			"fields": fieldBoosts.multiMatchFields(),
+			"type":   "best_fields",
+		}
+		if filters.Fuzziness != "" {
+			multiMatch["fuzziness"] = filters.Fuzziness
+		}
+
 		must = append(must, map[string]interface{}{
-			"match": map[string]interface{}{
-				"product_name": filters.ProductName,
-			},
+			"multi_match": multiMatch,
 		})
 	}
 
+	// Match по названию
+	if filters.ProductName != "" {
+		must = append(must, buildMatchQuery("product_name", filters.ProductName, filters.Fuzziness))
+	}
+
 	// Match по бренду
 	if filters.Brand != "" {
-		must = append(must, map[string]interface{}{
-			"match": map[string]interface{}{
-				"brand": filters.Brand,
-			},
-		})
+		must = append(must, buildMatchQuery("brand", filters.Brand, filters.Fuzziness))
 	}
 
 	// Match по категории
 	if filters.Category != "" {
-		must = append(must, map[string]interface{}{
-			"match": map[string]interface{}{
-				"category": filters.Category,
-			},
-		})
+		must = append(must, buildMatchQuery("category", filters.Category, filters.Fuzziness))
 	}
 
 	// Фильтр по цене
@@ -80,6 +332,9 @@ func (repo *SearchRepository) SearchAdvertisements(ctx context.Context, filters
 
 	// Собираем query
 	query := map[string]interface{}{
+		"from": pagination.From,
+		"size": pagination.Size,
+		"sort": buildSortClause(filters.Sort),
 		"query": map[string]interface{}{
 			"bool": map[string]interface{}{
 				"must":   must,
@@ -88,44 +343,54 @@ func (repo *SearchRepository) SearchAdvertisements(ctx context.Context, filters
 		},
 	}
 
-	body, err := json.Marshal(query)
-	if err != nil {
-		return nil, fmt.Errorf("ошибка сериализации запроса: %w", err)
+	// Выбранные фасеты фильтруют только выдачу, но не агрегации
+	if postFilter := buildPostFilter(filters.FacetSelections); postFilter != nil {
+		query["post_filter"] = postFilter
 	}
 
-	// Выполнение запроса
-	res, err := repo.client.Search(
-		repo.client.Search.WithContext(ctx),
-		repo.client.Search.WithIndex(repo.index),
-		repo.client.Search.WithBody(bytes.NewReader(body)),
-		repo.client.Search.WithTrackTotalHits(true),
-	)
-	if err != nil {
-		return nil, fmt.Errorf("ошибка поиска: %w", err)
+	aggs := make(map[string]interface{})
+	if filters.WithFacets {
+		for name, aggregation := range buildFacetAggregations(filters.FacetSelections) {
+			aggs[name] = aggregation
+		}
 	}
-	defer res.Body.Close()
-
-	if res.IsError() {
-		return nil, fmt.Errorf("ошибка ответа от ElasticSearch: %s", res.String())
+	if filters.PriceAggregations.Enabled() {
+		aggs[priceAggregationName] = buildPriceAggregations(filters.PriceAggregations, filters.FacetSelections)
 	}
-
-	// Парсим результат
-	var result struct {
-		Hits struct {
-			Hits []struct {
-				Source model.Advertisement `json:"_source"`
-			} `json:"hits"`
-		} `json:"hits"`
+	if len(aggs) > 0 {
+		query["aggs"] = aggs
 	}
 
-	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
-		return nil, fmt.Errorf("ошибка парсинга ответа: %w", err)
+	if filters.WithDidYouMean {
+		if suggest := buildDidYouMeanSuggest(filters); len(suggest) > 0 {
+			query["suggest"] = suggest
+		}
 	}
 
-	var ads []model.Advertisement
-	for _, hit := range result.Hits.Hits {
-		ads = append(ads, hit.Source)
+	return query
+}
+
+// buildSortClause переводит порядок сортировки в sort-клаузу Elasticsearch.
+// Последним ключом всегда идёт id, чтобы порядок был стабильным между страницами.
+func buildSortClause(order model.SortOrder) []map[string]interface{} {
+	var sort []map[string]interface{}
+
+	switch order {
+	case model.SortPriceAsc:
+		sort = append(sort, map[string]interface{}{"price": "asc"})
+	case model.SortPriceDesc:
+		sort = append(sort, map[string]interface{}{"price": "desc"})
+	case model.SortStockAsc:
+		sort = append(sort, map[string]interface{}{"stock": "asc"})
+	case model.SortStockDesc:
+		sort = append(sort, map[string]interface{}{"stock": "desc"})
+	case model.SortNameAsc:
+		sort = append(sort, map[string]interface{}{"product_name.keyword": "asc"})
+	case model.SortNameDesc:
+		sort = append(sort, map[string]interface{}{"product_name.keyword": "desc"})
+	default:
+		sort = append(sort, map[string]interface{}{"_score": "desc"})
 	}
 
-	return ads, nil
+	return append(sort, map[string]interface{}{"id": "asc"})
 }
@@ -0,0 +1,286 @@
+package repository
+
+import (
+	"SearchService/internal/model"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestBuildSortClause(t *testing.T) {
+	tests := []struct {
+		name  string
+		input model.SortOrder
+		want  []map[string]interface{}
+	}{
+		{
+			name:  "relevance",
+			input: model.SortRelevance,
+			want:  []map[string]interface{}{{"_score": "desc"}, {"id": "asc"}},
+		},
+		{
+			name:  "price descending",
+			input: model.SortPriceDesc,
+			want:  []map[string]interface{}{{"price": "desc"}, {"id": "asc"}},
+		},
+		{
+			name:  "name ascending",
+			input: model.SortNameAsc,
+			want:  []map[string]interface{}{{"product_name.keyword": "asc"}, {"id": "asc"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := buildSortClause(tt.input)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("buildSortClause() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildFacetAggregationsExcludesOwnSelection(t *testing.T) {
+	selections := map[model.Facet][]string{
+		model.FacetBrand: {"Herman Ltd"},
+		model.FacetColor: {"Bisque"},
+	}
+
+	aggs := buildFacetAggregations(selections)
+
+	brandFilter := aggs[string(model.FacetBrand)].(map[string]interface{})["filter"].(map[string]interface{})["bool"].(map[string]interface{})["filter"]
+	want := []map[string]interface{}{{"terms": map[string]interface{}{"color": []string{"Bisque"}}}}
+	if !reflect.DeepEqual(brandFilter, want) {
+		t.Errorf("brand facet filter = %v, want %v", brandFilter, want)
+	}
+
+	if postFilter := buildPostFilter(selections); postFilter == nil {
+		t.Error("buildPostFilter() = nil, want filter for selected facets")
+	}
+	if postFilter := buildPostFilter(nil); postFilter != nil {
+		t.Errorf("buildPostFilter(nil) = %v, want nil", postFilter)
+	}
+}
+
+func TestFacetsUseNormalizedFields(t *testing.T) {
+	selections := map[model.Facet][]string{model.FacetCategory: {"phones"}}
+	body, err := json.Marshal(buildFacetAggregations(selections)[string(model.FacetBrand)])
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"aggs":{"values":{"aggs":{"display":{"terms":{"field":"brand.keyword","size":1}}},` +
+		`"terms":{"field":"brand.normalized","size":20}}},` +
+		`"filter":{"bool":{"filter":[{"terms":{"category.normalized":["phones"]}}]}}}`
+	if string(body) != want {
+		t.Errorf("brand facet aggregation =\n%s\nwant\n%s", body, want)
+	}
+
+	aggregations := map[string]json.RawMessage{
+		"brand": json.RawMessage(`{"values":{"buckets":[` +
+			`{"key":"braun","doc_count":3,"display":{"buckets":[{"key":"Braun","doc_count":2}]}},` +
+			`{"key":"acme","doc_count":1,"display":{"buckets":[]}}]}}`),
+		"color": json.RawMessage(`{"values":{"buckets":[{"key":"Red","doc_count":4}]}}`),
+	}
+	facets, err := parseFacets(aggregations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantBrands := []model.FacetBucket{{Value: "Braun", Count: 3}, {Value: "acme", Count: 1}}
+	if !reflect.DeepEqual(facets[model.FacetBrand], wantBrands) {
+		t.Errorf("brand facet = %+v, want %+v", facets[model.FacetBrand], wantBrands)
+	}
+	if want := []model.FacetBucket{{Value: "Red", Count: 4}}; !reflect.DeepEqual(facets[model.FacetColor], want) {
+		t.Errorf("color facet = %+v, want %+v", facets[model.FacetColor], want)
+	}
+}
+
+func TestParseDidYouMean(t *testing.T) {
+	var suggest map[string][]termSuggestEntry
+	raw := `{
+		"brand": [{"text": "brawn", "options": [{"text": "braun"}]}],
+		"product_name": [
+			{"text": "smart", "options": []},
+			{"text": "fan", "options": [{"text": "fan"}]}
+		]
+	}`
+	if err := json.Unmarshal([]byte(raw), &suggest); err != nil {
+		t.Fatal(err)
+	}
+
+	got := parseDidYouMean(suggest)
+	want := map[string]string{"brand": "braun"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseDidYouMean() = %v, want %v", got, want)
+	}
+}
+
+func TestParseFieldBoosts(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:  "valid boosts",
+			input: "product_name:4, brand:2,description:0.5",
+			want: []string{
+				"brand^2",
+				"description.en^0.5", "description.ru^0.5", "description^0.5",
+				"product_name.en^4", "product_name.ru^4", "product_name^4",
+			},
+		},
+		{
+			name:    "unknown field",
+			input:   "price:2",
+			wantErr: true,
+		},
+		{
+			name:    "invalid boost",
+			input:   "brand:heavy",
+			wantErr: true,
+		},
+		{
+			name:    "missing separator",
+			input:   "brand",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseFieldBoosts(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseFieldBoosts() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got.multiMatchFields(), tt.want) {
+				t.Errorf("ParseFieldBoosts() fields = %v, want %v", got.multiMatchFields(), tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildPriceAggregations(t *testing.T) {
+	from, to := 0.0, 100.0
+	options := model.PriceAggregationOptions{
+		WithStats:         true,
+		HistogramInterval: 25,
+		Ranges:            []model.PriceRange{{From: &from, To: &to}, {From: &to}},
+	}
+	selections := map[model.Facet][]string{model.FacetBrand: {"Braun"}}
+
+	body, err := json.Marshal(buildPriceAggregations(options, selections))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"aggs":{` +
+		`"histogram":{"histogram":{"field":"price","interval":25,"min_doc_count":1}},` +
+		`"ranges":{"range":{"field":"price","ranges":[{"from":0,"to":100},{"from":100}]}},` +
+		`"stats":{"stats":{"field":"price"}}},` +
+		`"filter":{"bool":{"filter":[{"terms":{"brand.normalized":["Braun"]}}]}}}`
+	if string(body) != want {
+		t.Errorf("buildPriceAggregations() =\n%s\nwant\n%s", body, want)
+	}
+
+	body, _ = json.Marshal(buildPriceAggregations(model.PriceAggregationOptions{WithStats: true}, nil))
+	if want := `{"aggs":{"stats":{"stats":{"field":"price"}}},"filter":{"bool":{"filter":[]}}}`; string(body) != want {
+		t.Errorf("buildPriceAggregations() with stats only = %s, want %s", body, want)
+	}
+}
+
+func TestParsePriceAggregations(t *testing.T) {
+	raw := json.RawMessage(`{
+		"doc_count": 3,
+		"stats": {"count": 3, "min": 10, "max": 120, "avg": 50},
+		"histogram": {"buckets": [{"key": 0, "doc_count": 2}, {"key": 100, "doc_count": 1}]},
+		"ranges": {"buckets": [{"to": 100, "doc_count": 2}, {"from": 100, "doc_count": 1}]}
+	}`)
+	options := model.PriceAggregationOptions{WithStats: true, HistogramInterval: 100}
+
+	got, err := parsePriceAggregations(raw, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got.Stats == nil || got.Stats.Count != 3 || *got.Stats.Min != 10 || *got.Stats.Max != 120 || *got.Stats.Avg != 50 {
+		t.Errorf("Stats = %+v, want count 3, min 10, max 120, avg 50", got.Stats)
+	}
+	if len(got.Histogram) != 2 || *got.Histogram[1].From != 100 || *got.Histogram[1].To != 200 || got.Histogram[1].Count != 1 {
+		t.Errorf("Histogram = %+v, want 2 buckets, the second [100, 200) with 1 document", got.Histogram)
+	}
+	if len(got.Ranges) != 2 || got.Ranges[0].From != nil || *got.Ranges[0].To != 100 || got.Ranges[1].To != nil {
+		t.Errorf("Ranges = %+v, want open-ended ranges (-, 100) and [100, -)", got.Ranges)
+	}
+
+	withoutStats, err := parsePriceAggregations(json.RawMessage(`{"doc_count": 0}`), model.PriceAggregationOptions{HistogramInterval: 10})
+	if err != nil || withoutStats.Stats != nil || len(withoutStats.Histogram) != 0 {
+		t.Errorf("parsePriceAggregations() of empty result = %+v, %v; want no stats and no buckets", withoutStats, err)
+	}
+
+	if _, err := parsePriceAggregations(json.RawMessage(`{"stats": []}`), options); err == nil {
+		t.Error("parsePriceAggregations() of malformed result error = nil, want error")
+	}
+}
+
+func TestHistogramInterval(t *testing.T) {
+	tests := []struct {
+		name      string
+		requested float64
+		min       *float64
+		max       *float64
+		want      float64
+	}{
+		{name: "empty result", requested: 1, want: 1},
+		{name: "fits into bucket limit", requested: 10, min: price(0), max: price(5000), want: 10},
+		{name: "exactly at bucket limit", requested: 1, min: price(0), max: price(999), want: 1},
+		{name: "wide range widens interval", requested: 1, min: price(0), max: price(1_000_000), want: 1002},
+		{name: "offset range", requested: 5, min: price(100_000), max: price(200_000), want: 101},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := histogramInterval(tt.requested, tt.min, tt.max)
+			if got != tt.want {
+				t.Errorf("histogramInterval() = %v, want %v", got, tt.want)
+			}
+			if tt.min != nil && (*tt.max-*tt.min)/got+1 > model.MaxPriceHistogramBuckets {
+				t.Errorf("interval %v gives more than %d buckets", got, model.MaxPriceHistogramBuckets)
+			}
+		})
+	}
+}
+
+func TestBuildPriceSpanQuery(t *testing.T) {
+	filters := model.SearchFilters{
+		Brand:             "Acme",
+		Sort:              model.SortPriceAsc,
+		WithFacets:        true,
+		FacetSelections:   map[model.Facet][]string{model.FacetColor: {"red"}},
+		PriceAggregations: model.PriceAggregationOptions{HistogramInterval: 1},
+	}
+
+	query := buildPriceSpanQuery(filters, DefaultFieldBoosts)
+
+	if query["size"] != 0 {
+		t.Errorf("size = %v, want 0", query["size"])
+	}
+	for _, key := range []string{"sort", "post_filter", "suggest"} {
+		if _, ok := query[key]; ok {
+			t.Errorf("span query contains %s", key)
+		}
+	}
+
+	aggs, _ := query["aggs"].(map[string]interface{})
+	if len(aggs) != 1 {
+		t.Fatalf("aggs = %v, want only %s", aggs, priceAggregationName)
+	}
+	encoded, _ := json.Marshal(aggs[priceAggregationName])
+	want := `{"aggs":{"stats":{"stats":{"field":"price"}}},"filter":{"bool":{"filter":[{"terms":{"color":["red"]}}]}}}`
+	if string(encoded) != want {
+		t.Errorf("price aggregation = %s, want %s", encoded, want)
+	}
+}
+
+func price(value float64) *float64 {
+	return &value
+}
@@ -0,0 +1,91 @@
+package repository
+
+// search_suggestions содержит подсказки исправления запроса ("did you mean") на базе term suggester
+
+import (
+	"SearchService/internal/model"
+	"strings"
+)
+
+// termSuggestEntry — один токен исходного текста и варианты его исправления
+type termSuggestEntry struct {
+	Text    string `json:"text"`
+	Options []struct {
+		Text string `json:"text"`
+	} `json:"options"`
+}
+
+// suggestSource — текст, для которого ищется исправление, и поле индекса со словарём терминов
+type suggestSource struct {
+	field string
+	text  string
+}
+
+// buildDidYouMeanSuggest строит term suggester для каждого заполненного текстового фильтра
+func buildDidYouMeanSuggest(filters model.SearchFilters) map[string]interface{} {
+	suggest := make(map[string]interface{})
+
+	for name, source := range textFilters(filters) {
+		suggest[name] = map[string]interface{}{
+			"text": source.text,
+			"term": map[string]interface{}{
+				"field":        source.field,
+				"suggest_mode": "popular",
+				"sort":         "frequency",
+			},
+		}
+	}
+
+	return suggest
+}
+
+// parseDidYouMean собирает исправленный текст по каждому параметру запроса.
+// Параметр попадает в результат, только если хотя бы один токен был заменён.
+func parseDidYouMean(suggest map[string][]termSuggestEntry) map[string]string {
+	didYouMean := make(map[string]string)
+
+	for field, entries := range suggest {
+		corrected := make([]string, 0, len(entries))
+		changed := false
+
+		for _, entry := range entries {
+			if len(entry.Options) > 0 && entry.Options[0].Text != entry.Text {
+				corrected = append(corrected, entry.Options[0].Text)
+				changed = true
+				continue
+			}
+			corrected = append(corrected, entry.Text)
+		}
+
+		if changed {
+			didYouMean[field] = strings.Join(corrected, " ")
+		}
+	}
+
+	if len(didYouMean) == 0 {
+		return nil
+	}
+
+	return didYouMean
+}
+
+// textFilters возвращает заполненные текстовые параметры запроса: имя параметра -> источник подсказки.
+// Для свободного текста q словарём служат названия товаров.
+func textFilters(filters model.SearchFilters) map[string]suggestSource {
+	texts := make(map[string]suggestSource)
+
+	if filters.Query != "" {
+		texts["q"] = suggestSource{field: "product_name", text: filters.Query}
+	}
+	if filters.ProductName != "" {
+		texts["product_name"] = suggestSource{field: "product_name", text: filters.ProductName}
+	}
+	if filters.Brand != "" {
+		texts["brand"] = suggestSource{field: "brand", text: filters.Brand}
+	}
+	if filters.Category != "" {
+		texts["category"] = suggestSource{field: "category", text: filters.Category}
+	}
+
+	return texts
+}
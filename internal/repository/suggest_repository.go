@@ -0,0 +1,130 @@
+package repository
+
+// suggest_repository содержит автодополнение по полям search_as_you_type индекса объявлений
+
+import (
+	"SearchService/internal/model"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// suggestField описывает поле, по которому строятся подсказки
+type suggestField struct {
+	// source — поле документа, значение которого возвращается пользователю
+	source string
+	// suggest — поле search_as_you_type с той же строкой
+	suggest string
+}
+
+var (
+	nameSuggestField  = suggestField{source: "product_name", suggest: "name_suggest"}
+	brandSuggestField = suggestField{source: "brand", suggest: "brand_suggest"}
+)
+
+// SuggestAdvertisements возвращает дополнения названий товаров и брендов для префикса.
+// Оба запроса отправляются одним _msearch, дубликаты схлопываются через collapse по подполю normalized,
+// поэтому "Apple" и "apple" дают одну подсказку.
+func (repo *SearchRepository) SuggestAdvertisements(ctx context.Context, request model.SuggestRequest) (model.SuggestResult, error) {
+	var body bytes.Buffer
+	for _, field := range []suggestField{nameSuggestField, brandSuggestField} {
+		header, _ := json.Marshal(map[string]string{"index": repo.index})
+		body.Write(header)
+		body.WriteByte('\n')
+
+		query, err := json.Marshal(buildSuggestQuery(field, request))
+		if err != nil {
+			return model.SuggestResult{}, fmt.Errorf("ошибка сериализации запроса: %w", err)
+		}
+		body.Write(query)
+		body.WriteByte('\n')
+	}
+
+	res, err := repo.client.Msearch(
+		bytes.NewReader(body.Bytes()),
+		repo.client.Msearch.WithContext(ctx),
+	)
+	if err != nil {
+		return model.SuggestResult{}, fmt.Errorf("ошибка поиска подсказок: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return model.SuggestResult{}, fmt.Errorf("ошибка ответа от ElasticSearch: %s", res.String())
+	}
+
+	var result struct {
+		Responses []struct {
+			Error json.RawMessage `json:"error"`
+			Hits  struct {
+				Hits []struct {
+					Source map[string]interface{} `json:"_source"`
+				} `json:"hits"`
+			} `json:"hits"`
+		} `json:"responses"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return model.SuggestResult{}, fmt.Errorf("ошибка парсинга ответа: %w", err)
+	}
+	if len(result.Responses) != 2 {
+		return model.SuggestResult{}, fmt.Errorf("неожиданное количество ответов _msearch: %d", len(result.Responses))
+	}
+
+	values := make([][]string, 0, len(result.Responses))
+	for i, field := range []suggestField{nameSuggestField, brandSuggestField} {
+		response := result.Responses[i]
+		if len(response.Error) > 0 {
+			return model.SuggestResult{}, fmt.Errorf("ошибка подсказок по полю %s: %s", field.source, response.Error)
+		}
+
+		fieldValues := make([]string, 0, len(response.Hits.Hits))
+		for _, hit := range response.Hits.Hits {
+			if value, ok := hit.Source[field.source].(string); ok && value != "" {
+				fieldValues = append(fieldValues, value)
+			}
+		}
+		values = append(values, fieldValues)
+	}
+
+	return model.SuggestResult{
+		ProductNames: values[0],
+		Brands:       values[1],
+	}, nil
+}
+
+// buildSuggestQuery строит запрос bool_prefix по полю search_as_you_type и его n-граммным подполям
+func buildSuggestQuery(field suggestField, request model.SuggestRequest) map[string]interface{} {
+	filter := make([]map[string]interface{}, 0, 1)
+	if request.Category != "" {
+		filter = append(filter, map[string]interface{}{
+			"term": map[string]interface{}{
+				"category.normalized": request.Category,
+			},
+		})
+	}
+
+	return map[string]interface{}{
+		"size":    request.Limit,
+		"_source": []string{field.source},
+		"query": map[string]interface{}{
+			"bool": map[string]interface{}{
+				"must": map[string]interface{}{
+					"multi_match": map[string]interface{}{
+						"query": request.Prefix,
+						"type":  "bool_prefix",
+						"fields": []string{
+							field.suggest,
+							field.suggest + "._2gram",
+							field.suggest + "._3gram",
+						},
+					},
+				},
+				"filter": filter,
+			},
+		},
+		"collapse": map[string]interface{}{
+			"field": field.source + ".normalized",
+		},
+	}
+}
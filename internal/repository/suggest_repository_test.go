@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"SearchService/internal/model"
+	"encoding/json"
+	"testing"
+)
+
+func TestBuildSuggestQuery(t *testing.T) {
+	tests := []struct {
+		name    string
+		field   suggestField
+		request model.SuggestRequest
+		want    string
+	}{
+		{
+			name:    "product names",
+			field:   nameSuggestField,
+			request: model.SuggestRequest{Prefix: "sam", Limit: 5},
+			want: `{"_source":["product_name"],"collapse":{"field":"product_name.normalized"},` +
+				`"query":{"bool":{"filter":[],"must":{"multi_match":{` +
+				`"fields":["name_suggest","name_suggest._2gram","name_suggest._3gram"],"query":"sam","type":"bool_prefix"}}}},` +
+				`"size":5}`,
+		},
+		{
+			name:    "brands within category",
+			field:   brandSuggestField,
+			request: model.SuggestRequest{Prefix: "sam", Category: "Phones", Limit: 10},
+			want: `{"_source":["brand"],"collapse":{"field":"brand.normalized"},` +
+				`"query":{"bool":{"filter":[{"term":{"category.normalized":"Phones"}}],"must":{"multi_match":{` +
+				`"fields":["brand_suggest","brand_suggest._2gram","brand_suggest._3gram"],"query":"sam","type":"bool_prefix"}}}},` +
+				`"size":10}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := json.Marshal(buildSuggestQuery(tt.field, tt.request))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("buildSuggestQuery() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
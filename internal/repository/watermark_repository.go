@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"SearchService/internal"
+	"SearchService/internal/model"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// WatermarkRepository хранит позиции инкрементальной синхронизации в таблице sync_watermarks
+type WatermarkRepository struct {
+	Database *internal.Database
+}
+
+func NewWatermarkRepository(database *internal.Database) *WatermarkRepository {
+	return &WatermarkRepository{Database: database}
+}
+
+func (repo *WatermarkRepository) GetWatermark(name string) (model.SyncWatermark, bool, error) {
+	var watermark model.SyncWatermark
+	err := repo.Database.DB.Get(&watermark, `SELECT updated_at, last_id FROM sync_watermarks WHERE name = $1`, name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return model.SyncWatermark{}, false, nil
+	}
+	if err != nil {
+		return model.SyncWatermark{}, false, fmt.Errorf("ошибка загрузки отметки синхронизации %s: %w", name, err)
+	}
+
+	return watermark, true, nil
+}
+
+func (repo *WatermarkRepository) SaveWatermark(name string, watermark model.SyncWatermark) error {
+	query := `
+		INSERT INTO sync_watermarks (name, updated_at, last_id, synced_at)
+		VALUES ($1, $2, $3, now())
+		ON CONFLICT (name) DO UPDATE
+		SET updated_at = EXCLUDED.updated_at, last_id = EXCLUDED.last_id, synced_at = EXCLUDED.synced_at
+	`
+
+	if _, err := repo.Database.DB.Exec(query, name, watermark.UpdatedAt, watermark.ID); err != nil {
+		return fmt.Errorf("ошибка сохранения отметки синхронизации %s: %w", name, err)
+	}
+
+	return nil
+}
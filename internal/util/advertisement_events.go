@@ -0,0 +1,39 @@
+package util
+
+// advertisement_events содержит применение событий об изменении объявлений к индексу advertisements
+
+import (
+	"SearchService/internal/model"
+	"SearchService/internal/ports"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/elastic/go-elasticsearch/v8"
+)
+
+// ApplyAdvertisementEvent приводит документ в индексе в соответствие с событием.
+// Для create и update используется объявление из события, а если его нет — актуальная строка из БД;
+// если строка уже удалена, документ удаляется из индекса. Событие неизвестного типа не применяется.
+func ApplyAdvertisementEvent(esClient *elasticsearch.Client, loader ports.AdvertisementBatchLoader, event model.AdvertisementEvent) error {
+	switch event.Type {
+	case model.AdvertisementDeleted:
+		return deleteAdvertisement(esClient, event.ID)
+	case model.AdvertisementCreated, model.AdvertisementUpdated:
+	default:
+		return fmt.Errorf("неизвестный тип события: %q", event.Type)
+	}
+
+	advertisement := event.Advertisement
+	if advertisement == nil {
+		loaded, err := loader.GetAdvertisementById(event.ID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return deleteAdvertisement(esClient, event.ID)
+		}
+		if err != nil {
+			return fmt.Errorf("ошибка получения объявления %d: %w", event.ID, err)
+		}
+		advertisement = &loaded
+	}
+
+	return indexAdvertisement(esClient, advertisement, false)
+}
@@ -0,0 +1,160 @@
+package util
+
+import (
+	"SearchService/internal/model"
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/elastic/go-elasticsearch/v8"
+	"io"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+// documentTransport запоминает запросы к документам индекса и отвечает на них статусом status
+type documentTransport struct {
+	status   int
+	requests []string
+	// names — product_name проиндексированных документов в порядке запросов
+	names []string
+}
+
+func (transport *documentTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	transport.requests = append(transport.requests, request.Method+" "+request.URL.Path)
+
+	if request.Body != nil {
+		var document struct {
+			ProductName string `json:"product_name"`
+		}
+		if err := json.NewDecoder(request.Body).Decode(&document); err == nil {
+			transport.names = append(transport.names, document.ProductName)
+		}
+	}
+
+	status := transport.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{"X-Elastic-Product": []string{"Elasticsearch"}, "Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(bytes.NewReader([]byte(`{"result":"updated"}`))),
+	}, nil
+}
+
+// rowLoader — загрузчик, у которого отсутствующая строка даёт sql.ErrNoRows, как у репозитория
+type rowLoader struct {
+	sliceLoader
+}
+
+func (loader *rowLoader) GetAdvertisementById(id int) (model.Advertisement, error) {
+	for _, advertisement := range loader.advertisements {
+		if advertisement.Index == id {
+			return advertisement, nil
+		}
+	}
+	return model.Advertisement{}, fmt.Errorf("объявление %d: %w", id, sql.ErrNoRows)
+}
+
+func TestApplyAdvertisementEvent(t *testing.T) {
+	loader := &rowLoader{sliceLoader{advertisements: []model.Advertisement{{Index: 7, Name: "Stored product"}}}}
+
+	tests := []struct {
+		name         string
+		event        string
+		status       int
+		wantRequests []string
+		wantNames    []string
+		wantErr      bool
+	}{
+		{
+			name:         "create with payload",
+			event:        `{"type":"create","id":5,"advertisement":{"product_name":"New product"}}`,
+			wantRequests: []string{"PUT /advertisements/_doc/5"},
+			wantNames:    []string{"New product"},
+		},
+		{
+			name:         "update loads row from database",
+			event:        `{"type":"update","id":7}`,
+			wantRequests: []string{"PUT /advertisements/_doc/7"},
+			wantNames:    []string{"Stored product"},
+		},
+		{
+			name:         "update of removed row deletes document",
+			event:        `{"type":"update","id":8}`,
+			wantRequests: []string{"DELETE /advertisements/_doc/8"},
+		},
+		{
+			name:         "delete",
+			event:        `{"type":"delete","id":7}`,
+			wantRequests: []string{"DELETE /advertisements/_doc/7"},
+		},
+		{
+			name:         "rejected by elasticsearch",
+			event:        `{"type":"update","id":5,"advertisement":{"product_name":"New product"}}`,
+			status:       http.StatusBadRequest,
+			wantRequests: []string{"PUT /advertisements/_doc/5"},
+			wantNames:    []string{"New product"},
+			wantErr:      true,
+		},
+		{
+			name:    "unknown type",
+			event:   `{"type":"archive","id":5}`,
+			wantErr: true,
+		},
+		{
+			name:    "malformed payload",
+			event:   `{"type":"update","id":5,"advertisement":[1]}`,
+			wantErr: true,
+		},
+		{
+			name:    "payload for another advertisement",
+			event:   `{"type":"update","id":5,"advertisement":{"id":6}}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport := &documentTransport{status: tt.status}
+			esClient, err := elasticsearch.NewClient(elasticsearch.Config{Transport: transport})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Событие применяется так же, как в consumer'е: сначала разбор, затем запись в индекс
+			event, err := model.ParseAdvertisementEvent([]byte(tt.event))
+			if err == nil {
+				err = ApplyAdvertisementEvent(esClient, loader, event)
+			}
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(transport.requests, tt.wantRequests) {
+				t.Errorf("requests = %v, want %v", transport.requests, tt.wantRequests)
+			}
+			if !reflect.DeepEqual(transport.names, tt.wantNames) {
+				t.Errorf("indexed names = %v, want %v", transport.names, tt.wantNames)
+			}
+		})
+	}
+
+	t.Run("unknown type is not applied", func(t *testing.T) {
+		transport := &documentTransport{}
+		esClient, err := elasticsearch.NewClient(elasticsearch.Config{Transport: transport})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		event := model.AdvertisementEvent{Type: "archive", ID: 7}
+		if err := ApplyAdvertisementEvent(esClient, loader, event); err == nil {
+			t.Error("ApplyAdvertisementEvent() error = nil, want unknown type error")
+		}
+		if len(transport.requests) != 0 {
+			t.Errorf("requests = %v, want none", transport.requests)
+		}
+	})
+}
@@ -0,0 +1,242 @@
+package util
+
+// bulk содержит отправку операций в Bulk API с разбором результата по каждому документу:
+// временные отказы (429, 503) повторяются с экспоненциальной задержкой, остальные попадают в отчёт
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/elastic/go-elasticsearch/v8"
+	"io"
+	"log"
+	"net/http"
+	"time"
+)
+
+// BulkRetryPolicy — правила повтора временно отклонённых операций Bulk API
+type BulkRetryPolicy struct {
+	MaxRetries     int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+// DefaultBulkRetryPolicy используется при индексации, если не задана другая политика
+var DefaultBulkRetryPolicy = BulkRetryPolicy{
+	MaxRetries:     5,
+	InitialBackoff: 500 * time.Millisecond,
+	MaxBackoff:     30 * time.Second,
+}
+
+// backoff возвращает задержку перед повтором с номером attempt (начиная с 0)
+func (policy BulkRetryPolicy) backoff(attempt int) time.Duration {
+	delay := policy.InitialBackoff << attempt
+	if delay <= 0 || delay > policy.MaxBackoff {
+		return policy.MaxBackoff
+	}
+	return delay
+}
+
+// BulkFailure — документ, который не удалось проиндексировать
+type BulkFailure struct {
+	DocumentID string `json:"document_id"`
+	Status     int    `json:"status"`
+	Reason     string `json:"reason"`
+}
+
+// IndexingReport — итог индексации: количество успешных и неудачных операций и причины отказов
+type IndexingReport struct {
+	Indexed  int           `json:"indexed"`
+	Deleted  int           `json:"deleted"`
+	Failed   int           `json:"failed"`
+	Failures []BulkFailure `json:"failures,omitempty"`
+}
+
+func (report *IndexingReport) add(other IndexingReport) {
+	report.Indexed += other.Indexed
+	report.Deleted += other.Deleted
+	report.Failed += other.Failed
+	report.Failures = append(report.Failures, other.Failures...)
+}
+
+func (report *IndexingReport) fail(failure BulkFailure) {
+	report.Failed++
+	report.Failures = append(report.Failures, failure)
+}
+
+// bulkAction — одна операция Bulk API: строка метаданных и, для index, строка документа
+type bulkAction struct {
+	documentID string
+	lines      []byte
+}
+
+func newIndexAction(index string, documentID string, document interface{}) (bulkAction, error) {
+	meta, err := json.Marshal(map[string]map[string]string{
+		"index": {"_index": index, "_id": documentID},
+	})
+	if err != nil {
+		return bulkAction{}, fmt.Errorf("ошибка сериализации метаданных документа %s: %w", documentID, err)
+	}
+
+	body, err := json.Marshal(document)
+	if err != nil {
+		return bulkAction{}, fmt.Errorf("ошибка сериализации документа %s: %w", documentID, err)
+	}
+
+	lines := make([]byte, 0, len(meta)+len(body)+2)
+	lines = append(lines, meta...)
+	lines = append(lines, '\n')
+	lines = append(lines, body...)
+	lines = append(lines, '\n')
+
+	return bulkAction{documentID: documentID, lines: lines}, nil
+}
+
+// newDeleteAction формирует операцию удаления документа: у delete есть только строка метаданных
+func newDeleteAction(index string, documentID string) (bulkAction, error) {
+	meta, err := json.Marshal(map[string]map[string]string{
+		"delete": {"_index": index, "_id": documentID},
+	})
+	if err != nil {
+		return bulkAction{}, fmt.Errorf("ошибка сериализации метаданных документа %s: %w", documentID, err)
+	}
+
+	return bulkAction{documentID: documentID, lines: append(meta, '\n')}, nil
+}
+
+// bulkItemResult — результат одной операции из поля items ответа Bulk API
+type bulkItemResult struct {
+	// Op — тип операции (index, delete, ...), ключ элемента items
+	Op     string `json:"-"`
+	ID     string `json:"_id"`
+	Status int    `json:"status"`
+	Result string `json:"result"`
+	Error  *struct {
+		Type   string `json:"type"`
+		Reason string `json:"reason"`
+	} `json:"error"`
+}
+
+func (item bulkItemResult) reason() string {
+	if item.Error == nil {
+		return http.StatusText(item.Status)
+	}
+	return item.Error.Type + ": " + item.Error.Reason
+}
+
+// succeeded сообщает, выполнена ли операция. Удаление отсутствующего документа тоже считается успехом.
+func (item bulkItemResult) succeeded() bool {
+	if item.Op == "delete" && item.Status == http.StatusNotFound && item.Result == "not_found" {
+		return true
+	}
+	return item.Status >= 200 && item.Status < 300
+}
+
+func isRetryableStatus(status int) bool {
+	return status == http.StatusTooManyRequests || status == http.StatusServiceUnavailable
+}
+
+// bulkRequestError — ошибка Bulk API для запроса целиком (а не для отдельных документов)
+type bulkRequestError struct {
+	status int
+	body   string
+}
+
+func (err *bulkRequestError) Error() string {
+	return fmt.Sprintf("ошибка Bulk API: [%d] %s", err.status, err.body)
+}
+
+// executeBulk отправляет операции в Bulk API и повторяет временно отклонённые согласно policy.
+// Ошибка возвращается, только если не удалось выполнить запрос целиком; отказы по отдельным
+// документам попадают в отчёт.
+func executeBulk(esClient *elasticsearch.Client, actions []bulkAction, policy BulkRetryPolicy) (IndexingReport, error) {
+	var report IndexingReport
+	pending := actions
+
+	for attempt := 0; len(pending) > 0; attempt++ {
+		canRetry := attempt < policy.MaxRetries
+
+		items, err := sendBulk(esClient, pending)
+		if err != nil {
+			requestErr, ok := err.(*bulkRequestError)
+			if !ok || !isRetryableStatus(requestErr.status) || !canRetry {
+				return report, err
+			}
+
+			log.Printf("Bulk API временно недоступен (%d), повтор через %s", requestErr.status, policy.backoff(attempt))
+			time.Sleep(policy.backoff(attempt))
+			continue
+		}
+
+		var retry []bulkAction
+		for i, item := range items {
+			switch {
+			case item.succeeded() && item.Op == "delete":
+				report.Deleted++
+			case item.succeeded():
+				report.Indexed++
+			case isRetryableStatus(item.Status) && canRetry:
+				retry = append(retry, pending[i])
+			default:
+				report.fail(BulkFailure{DocumentID: pending[i].documentID, Status: item.Status, Reason: item.reason()})
+			}
+		}
+
+		if len(retry) > 0 {
+			log.Printf("%d документов временно отклонено, повтор через %s", len(retry), policy.backoff(attempt))
+			time.Sleep(policy.backoff(attempt))
+		}
+		pending = retry
+	}
+
+	return report, nil
+}
+
+// sendBulk выполняет один запрос к Bulk API и возвращает результаты в порядке операций
+func sendBulk(esClient *elasticsearch.Client, actions []bulkAction) ([]bulkItemResult, error) {
+	var buffer bytes.Buffer
+	for _, action := range actions {
+		buffer.Write(action.lines)
+	}
+
+	response, err := esClient.Bulk(bytes.NewReader(buffer.Bytes()))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка bulk вставки: %w", err)
+	}
+	defer response.Body.Close()
+
+	if response.IsError() {
+		return nil, &bulkRequestError{status: response.StatusCode, body: response.String()}
+	}
+
+	items, err := parseBulkResponse(response.Body)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) != len(actions) {
+		return nil, fmt.Errorf("ответ Bulk API содержит %d результатов на %d операций", len(items), len(actions))
+	}
+
+	return items, nil
+}
+
+// parseBulkResponse извлекает результаты операций из ответа Bulk API.
+// Каждый элемент items — объект с единственным ключом по типу операции (index, delete, ...).
+func parseBulkResponse(body io.Reader) ([]bulkItemResult, error) {
+	var response struct {
+		Items []map[string]bulkItemResult `json:"items"`
+	}
+	if err := json.NewDecoder(body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("ошибка парсинга ответа Bulk API: %w", err)
+	}
+
+	items := make([]bulkItemResult, 0, len(response.Items))
+	for _, item := range response.Items {
+		for op, result := range item {
+			result.Op = op
+			items = append(items, result)
+		}
+	}
+
+	return items, nil
+}
@@ -0,0 +1,57 @@
+package util
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseBulkResponse(t *testing.T) {
+	body := `{
+		"took": 3,
+		"errors": true,
+		"items": [
+			{"index": {"_index": "advertisements_v1", "_id": "1", "status": 201}},
+			{"index": {"_index": "advertisements_v1", "_id": "2", "status": 429,
+				"error": {"type": "es_rejected_execution_exception", "reason": "rejected execution"}}},
+			{"index": {"_index": "advertisements_v1", "_id": "3", "status": 400,
+				"error": {"type": "mapper_parsing_exception", "reason": "failed to parse field [price]"}}},
+			{"delete": {"_index": "advertisements_v1", "_id": "4", "status": 404, "result": "not_found"}}
+		]
+	}`
+
+	items, err := parseBulkResponse(strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("parseBulkResponse() error = %v", err)
+	}
+	if len(items) != 4 {
+		t.Fatalf("parseBulkResponse() returned %d items, want 4", len(items))
+	}
+
+	if items[0].Status != 201 || items[0].Error != nil || !items[0].succeeded() {
+		t.Errorf("item 1 = %+v, want success", items[0])
+	}
+	if !isRetryableStatus(items[1].Status) {
+		t.Errorf("item 2 status %d should be retryable", items[1].Status)
+	}
+	if isRetryableStatus(items[2].Status) {
+		t.Errorf("item 3 status %d should not be retryable", items[2].Status)
+	}
+	if want := "mapper_parsing_exception: failed to parse field [price]"; items[2].reason() != want {
+		t.Errorf("item 3 reason = %q, want %q", items[2].reason(), want)
+	}
+	if items[3].Op != "delete" || !items[3].succeeded() {
+		t.Errorf("item 4 = %+v, want successful delete of a missing document", items[3])
+	}
+}
+
+func TestBulkRetryPolicyBackoff(t *testing.T) {
+	policy := BulkRetryPolicy{MaxRetries: 10, InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+
+	want := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond, time.Second, time.Second}
+	for attempt, expected := range want {
+		if got := policy.backoff(attempt); got != expected {
+			t.Errorf("backoff(%d) = %s, want %s", attempt, got, expected)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package util
+
+// consistency содержит проверки содержимого индекса после загрузки: документы, в которых
+// обязательные поля оказались пустыми, означают, что данные из БД были загружены не полностью
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/elastic/go-elasticsearch/v8"
+	"strings"
+)
+
+// DefaultRequiredFields — поля документа, которые не должны быть пустыми после индексации.
+// description, ean, color и size необязательны: импорт и API принимают объявления без них.
+var DefaultRequiredFields = []string{"product_name", "brand", "category", "currency", "availability"}
+
+// blankFieldSamples — сколько id документов с пустым полем выводить в отчёте
+const blankFieldSamples = 5
+
+// BlankFieldReport — количество документов с пустым обязательным полем и примеры их id
+type BlankFieldReport struct {
+	Field     string   `json:"field"`
+	Count     int64    `json:"count"`
+	SampleIDs []string `json:"sample_ids"`
+}
+
+// CheckRequiredFields ищет в index документы, где поля из fields отсутствуют или пусты.
+// Возвращает отчёт только по полям, для которых такие документы нашлись.
+func CheckRequiredFields(esClient *elasticsearch.Client, index string, fields []string) ([]BlankFieldReport, error) {
+	var reports []BlankFieldReport
+
+	for _, field := range fields {
+		body, err := json.Marshal(map[string]interface{}{
+			"size":             blankFieldSamples,
+			"_source":          false,
+			"track_total_hits": true,
+			"query":            blankFieldQuery(field),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("ошибка сериализации запроса: %w", err)
+		}
+
+		response, err := esClient.Search(
+			esClient.Search.WithIndex(index),
+			esClient.Search.WithBody(bytes.NewReader(body)),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка проверки поля %s: %w", field, err)
+		}
+
+		var result struct {
+			Hits struct {
+				Total struct {
+					Value int64 `json:"value"`
+				} `json:"total"`
+				Hits []struct {
+					ID string `json:"_id"`
+				} `json:"hits"`
+			} `json:"hits"`
+		}
+		if response.IsError() {
+			response.Body.Close()
+			return nil, fmt.Errorf("ошибка проверки поля %s: %s", field, response.String())
+		}
+		err = json.NewDecoder(response.Body).Decode(&result)
+		response.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("ошибка парсинга ответа: %w", err)
+		}
+
+		if result.Hits.Total.Value == 0 {
+			continue
+		}
+
+		report := BlankFieldReport{Field: field, Count: result.Hits.Total.Value}
+		for _, hit := range result.Hits.Hits {
+			report.SampleIDs = append(report.SampleIDs, hit.ID)
+		}
+		reports = append(reports, report)
+	}
+
+	return reports, nil
+}
+
+// blankFieldQuery находит документы, где поле отсутствует или содержит пустую строку.
+// Пустая строка ищется точным term по keyword-представлению поля: у текстового поля она не даёт
+// ни одного термина, а wildcard по анализируемому тексту дорог и считает пустым любой текст без терминов,
+// например из одних знаков препинания. Отсутствие проверяется по самому полю, чтобы значения
+// длиннее ignore_above keyword-подполя не считались пустыми.
+func blankFieldQuery(field string) map[string]interface{} {
+	missing := map[string]interface{}{
+		"bool": map[string]interface{}{
+			"must_not": map[string]interface{}{
+				"exists": map[string]interface{}{"field": field},
+			},
+		},
+	}
+
+	keyword, found := keywordField(field)
+	if !found {
+		return missing
+	}
+
+	return map[string]interface{}{
+		"bool": map[string]interface{}{
+			"should": []interface{}{
+				missing,
+				map[string]interface{}{
+					"term": map[string]interface{}{keyword: ""},
+				},
+			},
+			"minimum_should_match": 1,
+		},
+	}
+}
+
+// keywordField возвращает keyword-представление поля по управляемому маппингу: само поле,
+// если оно keyword, или его подполе keyword. found = false, если такого представления нет.
+func keywordField(field string) (keyword string, found bool) {
+	var definition struct {
+		Mappings map[string]interface{} `json:"mappings"`
+	}
+	if err := json.Unmarshal(advertisementsIndexDefinition, &definition); err != nil {
+		return "", false
+	}
+
+	fields := flattenMappingFields(definition.Mappings, "")
+	for _, candidate := range []string{field, field + ".keyword"} {
+		if fields[candidate]["type"] == "keyword" {
+			return candidate, true
+		}
+	}
+
+	return "", false
+}
+
+// formatBlankFields описывает найденные пустые поля для сообщения об ошибке
+func formatBlankFields(reports []BlankFieldReport) string {
+	parts := make([]string, 0, len(reports))
+	for _, report := range reports {
+		parts = append(parts, fmt.Sprintf("%s: %d документов (например, %s)", report.Field, report.Count, strings.Join(report.SampleIDs, ", ")))
+	}
+	return strings.Join(parts, "; ")
+}
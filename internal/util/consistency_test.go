@@ -0,0 +1,131 @@
+package util
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/elastic/go-elasticsearch/v8"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// blankFieldTransport отвечает на поиск пустых полей: для каждого поля из blank возвращает
+// заданные id документов, для остальных полей — пустую выдачу
+type blankFieldTransport struct {
+	blank map[string][]string
+}
+
+func (transport *blankFieldTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	var body struct {
+		Query struct {
+			Bool struct {
+				Should []struct {
+					Bool struct {
+						MustNot struct {
+							Exists struct {
+								Field string `json:"field"`
+							} `json:"exists"`
+						} `json:"must_not"`
+					} `json:"bool"`
+				} `json:"should"`
+			} `json:"bool"`
+		} `json:"query"`
+	}
+	if err := json.NewDecoder(request.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+
+	var ids []string
+	for _, clause := range body.Query.Bool.Should {
+		if field := clause.Bool.MustNot.Exists.Field; field != "" {
+			ids = transport.blank[field]
+		}
+	}
+
+	hits := make([]string, 0, len(ids))
+	for _, id := range ids {
+		hits = append(hits, fmt.Sprintf(`{"_id":%q}`, id))
+	}
+	response := fmt.Sprintf(`{"hits":{"total":{"value":%d},"hits":[%s]}}`, len(ids), strings.Join(hits, ","))
+
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"X-Elastic-Product": []string{"Elasticsearch"}, "Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(bytes.NewReader([]byte(response))),
+	}, nil
+}
+
+func TestCheckRequiredFields(t *testing.T) {
+	transport := &blankFieldTransport{blank: map[string][]string{"brand": {"3", "8"}}}
+	esClient, err := elasticsearch.NewClient(elasticsearch.Config{Transport: transport})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reports, err := CheckRequiredFields(esClient, "advertisements_v1", []string{"product_name", "brand", "category"})
+	if err != nil {
+		t.Fatalf("CheckRequiredFields() error = %v", err)
+	}
+
+	want := []BlankFieldReport{{Field: "brand", Count: 2, SampleIDs: []string{"3", "8"}}}
+	if !reflect.DeepEqual(reports, want) {
+		t.Errorf("CheckRequiredFields() = %+v, want %+v", reports, want)
+	}
+}
+
+func TestBlankFieldQuery(t *testing.T) {
+	tests := []struct {
+		field string
+		want  string
+	}{
+		{
+			field: "brand",
+			want:  `{"bool":{"minimum_should_match":1,"should":[{"bool":{"must_not":{"exists":{"field":"brand"}}}},{"term":{"brand.keyword":""}}]}}`,
+		},
+		{
+			field: "currency",
+			want:  `{"bool":{"minimum_should_match":1,"should":[{"bool":{"must_not":{"exists":{"field":"currency"}}}},{"term":{"currency":""}}]}}`,
+		},
+		{
+			// У description нет keyword-представления, пустая строка не отличима от отсутствия значения
+			field: "description",
+			want:  `{"bool":{"must_not":{"exists":{"field":"description"}}}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.field, func(t *testing.T) {
+			encoded, err := json.Marshal(blankFieldQuery(tt.field))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(encoded) != tt.want {
+				t.Errorf("blankFieldQuery(%q) = %s, want %s", tt.field, encoded, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatBlankFields(t *testing.T) {
+	reports := []BlankFieldReport{
+		{Field: "brand", Count: 2, SampleIDs: []string{"3", "8"}},
+		{Field: "currency", Count: 1, SampleIDs: []string{"5"}},
+	}
+
+	want := "brand: 2 документов (например, 3, 8); currency: 1 документов (например, 5)"
+	if got := formatBlankFields(reports); got != want {
+		t.Errorf("formatBlankFields() = %q, want %q", got, want)
+	}
+}
+
+func TestDefaultRequiredFieldsExcludeOptionalColumns(t *testing.T) {
+	for _, field := range DefaultRequiredFields {
+		switch field {
+		case "description", "ean", "color", "size":
+			t.Errorf("DefaultRequiredFields contains optional field %s", field)
+		}
+	}
+}
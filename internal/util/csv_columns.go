@@ -0,0 +1,211 @@
+package util
+
+// csv_columns сопоставляет колонки CSV-файла полям объявления по заголовку, а не по позиции:
+// колонки могут идти в любом порядке, называться по-разному (см. CSVColumnAliases),
+// а необязательные могут отсутствовать. Ошибки заголовка обнаруживаются до вставки первой строки.
+
+import (
+	"SearchService/internal/model"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// CSVColumnAliases — допустимые названия колонок CSV для каждого поля объявления (ключ — имя колонки в БД).
+// Названия сравниваются без учёта регистра, а пробелы и дефисы приравниваются к подчёркиванию.
+type CSVColumnAliases map[string][]string
+
+// DefaultCSVColumnAliases — названия колонок, которые распознаются без дополнительной настройки
+var DefaultCSVColumnAliases = CSVColumnAliases{
+	"id":           {"id", "index"},
+	"product_name": {"product_name", "name", "product", "title"},
+	"description":  {"description"},
+	"brand":        {"brand"},
+	"category":     {"category"},
+	"price":        {"price"},
+	"currency":     {"currency"},
+	"stock":        {"stock", "quantity"},
+	"ean":          {"ean", "barcode", "gtin"},
+	"color":        {"color", "colour"},
+	"size":         {"size"},
+	"availability": {"availability"},
+}
+
+// requiredCSVColumns — поля, без колонок для которых файл не импортируется
+var requiredCSVColumns = []string{"product_name", "brand", "category", "price", "currency"}
+
+// ParseCSVColumnAliases разбирает дополнительные названия колонок в формате
+// "product_name:Наименование|Товар,ean:Штрихкод" и добавляет их к DefaultCSVColumnAliases.
+// Название, которое после нормализации указывает на разные поля, считается ошибкой.
+func ParseCSVColumnAliases(raw string) (CSVColumnAliases, error) {
+	aliases := make(CSVColumnAliases, len(DefaultCSVColumnAliases))
+	for field, names := range DefaultCSVColumnAliases {
+		aliases[field] = append([]string(nil), names...)
+	}
+
+	for _, pair := range strings.Split(raw, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		field, rawNames, found := strings.Cut(pair, ":")
+		if !found {
+			return nil, fmt.Errorf("неверный формат названий колонки: %q", pair)
+		}
+		field = strings.TrimSpace(field)
+		if _, known := DefaultCSVColumnAliases[field]; !known {
+			return nil, fmt.Errorf("неизвестное поле объявления %q", field)
+		}
+
+		for _, name := range strings.Split(rawNames, "|") {
+			if name = strings.TrimSpace(name); name != "" {
+				aliases[field] = append(aliases[field], name)
+			}
+		}
+	}
+
+	if _, err := aliases.fieldsByName(); err != nil {
+		return nil, err
+	}
+	return aliases, nil
+}
+
+// fieldsByName возвращает поле для каждого нормализованного названия колонки.
+// Если одно название указывает на разные поля, возвращается ошибка.
+func (aliases CSVColumnAliases) fieldsByName() (map[string]string, error) {
+	fields := make([]string, 0, len(aliases))
+	for field := range aliases {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	fieldsByName := make(map[string]string)
+	for _, field := range fields {
+		for _, name := range aliases[field] {
+			normalized := normalizeCSVColumnName(name)
+			if other, exists := fieldsByName[normalized]; exists && other != field {
+				return nil, fmt.Errorf("название колонки %q относится сразу к полям %s и %s", name, other, field)
+			}
+			fieldsByName[normalized] = field
+		}
+	}
+
+	return fieldsByName, nil
+}
+
+// CSVHeaderError описывает все проблемы заголовка CSV сразу, чтобы файл можно было исправить за один раз
+type CSVHeaderError struct {
+	Missing   []string
+	Unknown   []string
+	Duplicate []string
+}
+
+func (err *CSVHeaderError) Error() string {
+	var problems []string
+	if len(err.Missing) > 0 {
+		problems = append(problems, "нет обязательных колонок: "+strings.Join(err.Missing, ", "))
+	}
+	if len(err.Unknown) > 0 {
+		problems = append(problems, "неизвестные колонки: "+strings.Join(err.Unknown, ", "))
+	}
+	if len(err.Duplicate) > 0 {
+		problems = append(problems, "повторяющиеся колонки: "+strings.Join(err.Duplicate, ", "))
+	}
+	return "неверный заголовок CSV: " + strings.Join(problems, "; ")
+}
+
+// csvColumns — позиции колонок файла для полей объявления
+type csvColumns map[string]int
+
+// newCSVColumns сопоставляет колонки заголовка полям объявления
+func newCSVColumns(header []string, aliases CSVColumnAliases) (csvColumns, error) {
+	fieldsByName, err := aliases.fieldsByName()
+	if err != nil {
+		return nil, err
+	}
+
+	columns := make(csvColumns)
+	headerErr := &CSVHeaderError{}
+	for position, name := range header {
+		// Excel сохраняет CSV в UTF-8 с BOM перед первой колонкой
+		if position == 0 {
+			name = strings.TrimPrefix(name, "\ufeff")
+		}
+
+		field, known := fieldsByName[normalizeCSVColumnName(name)]
+		switch {
+		case !known:
+			headerErr.Unknown = append(headerErr.Unknown, strconv.Quote(name))
+		case columnPresent(columns, field):
+			headerErr.Duplicate = append(headerErr.Duplicate, field)
+		default:
+			columns[field] = position
+		}
+	}
+
+	for _, field := range requiredCSVColumns {
+		if !columnPresent(columns, field) {
+			headerErr.Missing = append(headerErr.Missing, field)
+		}
+	}
+	sort.Strings(headerErr.Duplicate)
+
+	if len(headerErr.Missing) > 0 || len(headerErr.Unknown) > 0 || len(headerErr.Duplicate) > 0 {
+		return nil, headerErr
+	}
+	return columns, nil
+}
+
+func columnPresent(columns csvColumns, field string) bool {
+	_, present := columns[field]
+	return present
+}
+
+func normalizeCSVColumnName(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	return strings.NewReplacer(" ", "_", "-", "_").Replace(name)
+}
+
+// value возвращает значение поля в записи или пустую строку, если колонки нет
+func (columns csvColumns) value(record []string, field string) string {
+	position, present := columns[field]
+	if !present || position >= len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[position])
+}
+
+// parseCSVRecord преобразует запись CSV в объявление. Пустые числовые значения необязательных колонок
+// (id, stock) считаются нулём.
+func parseCSVRecord(columns csvColumns, record []string) (model.Advertisement, error) {
+	advertisement := model.Advertisement{
+		Name:         columns.value(record, "product_name"),
+		Description:  columns.value(record, "description"),
+		Brand:        columns.value(record, "brand"),
+		Category:     columns.value(record, "category"),
+		Currency:     columns.value(record, "currency"),
+		Ean:          columns.value(record, "ean"),
+		Color:        columns.value(record, "color"),
+		Size:         columns.value(record, "size"),
+		Availability: columns.value(record, "availability"),
+	}
+
+	var err error
+	if raw := columns.value(record, "id"); raw != "" {
+		if advertisement.Index, err = strconv.Atoi(raw); err != nil {
+			return model.Advertisement{}, fmt.Errorf("неверный индекс: %v", err)
+		}
+	}
+	if advertisement.Price, err = strconv.ParseFloat(columns.value(record, "price"), 64); err != nil {
+		return model.Advertisement{}, fmt.Errorf("неверная цена: %v", err)
+	}
+	if raw := columns.value(record, "stock"); raw != "" {
+		if advertisement.Stock, err = strconv.Atoi(raw); err != nil {
+			return model.Advertisement{}, fmt.Errorf("неверный stock: %v", err)
+		}
+	}
+
+	return advertisement, nil
+}
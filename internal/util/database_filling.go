@@ -3,6 +3,8 @@ package util
 import (
 	"SearchService/internal"
 	"SearchService/internal/model"
+	"SearchService/internal/repository"
+	"context"
 	"encoding/csv"
 	"errors"
 	"fmt"
@@ -10,23 +12,29 @@ import (
 	"io"
 	"log"
 	"os"
-	"strconv"
 	"strings"
 	"sync"
 )
 
 type DatabaseFilling struct {
 	*internal.Database
+	// ColumnAliases — названия колонок CSV, по которым записи сопоставляются полям объявления
+	ColumnAliases CSVColumnAliases
 }
 
 type BatchTask struct {
 	Advertisements []model.Advertisement
+	// FirstLine и LastLine — номера строк файла с первой и последней записью пакета
+	FirstLine int
+	LastLine  int
 }
 
 func NewDatabaseFilling(database *internal.Database) *DatabaseFilling {
-	return &DatabaseFilling{Database: database}
+	return &DatabaseFilling{Database: database, ColumnAliases: DefaultCSVColumnAliases}
 }
 
+// ReadFileCSV проверяет, что файл целиком читается как CSV с заголовком. Содержимое строк не выводится:
+// в файлах импорта могут быть данные, которым не место в логах.
 func ReadFileCSV(filepath string) error {
 	file, err := os.Open(filepath)
 	if err != nil {
@@ -41,14 +49,12 @@ func ReadFileCSV(filepath string) error {
 	}
 
 	for {
-		record, err := reader.Read()
-		if err != nil {
+		if _, err := reader.Read(); err != nil {
 			if errors.Is(err, io.EOF) {
 				break
 			}
 			return fmt.Errorf("ошибка чтения файла: %w", err)
 		}
-		fmt.Println(record)
 	}
 
 	return nil
@@ -71,9 +77,14 @@ func (dbf *DatabaseFilling) FillDatabaseFromCSVSync(filepath string, batchSize i
 
 	reader := csv.NewReader(file)
 
-	if _, err := reader.Read(); err != nil {
+	header, err := reader.Read()
+	if err != nil {
 		return fmt.Errorf("не удалось прочитать заголовок: %w", err)
 	}
+	columns, err := newCSVColumns(header, dbf.ColumnAliases)
+	if err != nil {
+		return err
+	}
 
 	var advertisements []model.Advertisement
 	for {
@@ -84,7 +95,7 @@ func (dbf *DatabaseFilling) FillDatabaseFromCSVSync(filepath string, batchSize i
 			}
 			return fmt.Errorf("ошибка чтения CSV файла: %w", err)
 		}
-		advertisement, err := parseCSVRecord(record)
+		advertisement, err := parseCSVRecord(columns, record)
 		if err != nil {
 			return fmt.Errorf("ошибка парсинга данных: %w", err)
 		}
@@ -107,7 +118,15 @@ func (dbf *DatabaseFilling) FillDatabaseFromCSVSync(filepath string, batchSize i
 	return nil
 }
 
-// FillDatabaseFromCSV читает CSV-файл с данными об объявлениях и записывает их в базу данных пакетами заданного размера.
+// BatchResult — итог сохранения одного пакета строк CSV
+type BatchResult struct {
+	// Rows — количество строк в пакете
+	Rows int
+	// Err — ошибка сохранения пакета (FailedBatch с номерами строк); nil, если пакет сохранён
+	Err error
+}
+
+// FillDatabaseFromCSVAsync читает CSV-файл с данными об объявлениях и записывает их в базу данных пакетами заданного размера.
 // Для повышения производительности используется пул воркеров (горутин), каждая из которых асинхронно обрабатывает свой пакет.
 //
 // Параметры:
@@ -117,17 +136,33 @@ func (dbf *DatabaseFilling) FillDatabaseFromCSVSync(filepath string, batchSize i
 // Особенности:
 // - CSV-файл читается последовательно, данные группируются в пакеты и отправляются в канал задач.
 // - Несколько воркеров параллельно извлекают задачи из канала и сохраняют данные в базу.
+// - Пакет, нарушивший ограничения таблицы, пропускается, а его строки попадают в FillResult.FailedBatches.
+// - Фатальная ошибка (чтение или парсинг файла, недоступная БД) отменяет загрузку остальных пакетов.
 // - После завершения чтения все горутины завершаются корректно.
 //
-// Возвращает ошибку, если возникает проблема при чтении файла, парсинге данных или сохранении в базу.
+// Возвращает сводный результат (см. FillResult.Status) и фатальную ошибку, если она была.
+
+func (dbf *DatabaseFilling) FillDatabaseFromCSVAsync(filepath string, batchSize int) (FillResult, error) {
+	return dbf.FillDatabaseFromCSV(context.Background(), filepath, batchSize, nil)
+}
 
-func (dbf *DatabaseFilling) FillDatabaseFromCSVAsync(filepath string, batchSize int) error {
+// FillDatabaseFromCSV — FillDatabaseFromCSVAsync с отменой и отчётом о прогрессе.
+// onBatch, если задан, вызывается из воркеров после каждого сохранённого или неудачного пакета,
+// поэтому должен быть безопасен для конкурентного вызова. Отмена ctx останавливает чтение файла,
+// а ещё не сохранённые пакеты пропускаются; в этом случае возвращается ctx.Err().
+func (dbf *DatabaseFilling) FillDatabaseFromCSV(ctx context.Context, filepath string, batchSize int, onBatch func(BatchResult)) (FillResult, error) {
 	file, err := os.Open(filepath)
 	if err != nil {
-		return fmt.Errorf("файл не был найден: %w", err)
+		err = fmt.Errorf("файл не был найден: %w", err)
+		return FillResult{Err: err}, err
 	}
 	defer file.Close()
 
+	// Первая фатальная ошибка отменяет ctx и становится его причиной
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	collector := &fillCollector{}
 	tasks := make(chan BatchTask, 10)
 	waitGroup := &sync.WaitGroup{}
 	numWorkers := 4
@@ -137,50 +172,98 @@ func (dbf *DatabaseFilling) FillDatabaseFromCSVAsync(filepath string, batchSize
 		go func() {
 			defer waitGroup.Done()
 			for task := range tasks {
+				if ctx.Err() != nil {
+					continue
+				}
+				var batchErr error
 				if err := saveBatchToDatabase(dbf.Database, task.Advertisements); err != nil {
-					log.Printf("ошибка сохранения данных в БД: %v\n", err)
+					batchErr = FailedBatch{FirstLine: task.FirstLine, LastLine: task.LastLine, Err: err}
+					log.Printf("ошибка сохранения данных в БД: %v\n", batchErr)
+					if isFatalBatchError(err) {
+						cancel(batchErr)
+					}
+				}
+				collector.add(task, err)
+				if onBatch != nil {
+					onBatch(BatchResult{Rows: len(task.Advertisements), Err: batchErr})
 				}
 			}
 		}()
 	}
 
+	readCSVBatches(ctx, file, batchSize, dbf.ColumnAliases, tasks, cancel)
+	close(tasks)
+	waitGroup.Wait()
+
+	result := collector.result
+	result.Err = context.Cause(ctx)
+	return result, result.Err
+}
+
+// readCSVBatches читает файл, группирует записи в пакеты и отправляет их в tasks до конца файла
+// или отмены ctx. Ошибка заголовка, чтения или парсинга фатальна: она передаётся в cancel.
+// Заголовок проверяется до отправки первого пакета, поэтому при его ошибке в БД ничего не вставляется.
+func readCSVBatches(ctx context.Context, file io.Reader, batchSize int, aliases CSVColumnAliases,
+	tasks chan<- BatchTask, cancel context.CancelCauseFunc) {
 	reader := csv.NewReader(file)
 
-	if _, err := reader.Read(); err != nil {
-		return fmt.Errorf("не удалось прочитать заголовок: %w", err)
+	header, err := reader.Read()
+	if err != nil {
+		cancel(fmt.Errorf("не удалось прочитать заголовок: %w", err))
+		return
+	}
+	columns, err := newCSVColumns(header, aliases)
+	if err != nil {
+		cancel(err)
+		return
 	}
 
-	var advertisements []model.Advertisement
-	for {
+	task := BatchTask{}
+	send := func() bool {
+		select {
+		case tasks <- task:
+			// Отправленный пакет принадлежит воркеру, поэтому следующий собирается в новом срезе
+			task = BatchTask{}
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	for ctx.Err() == nil {
 		record, err := reader.Read()
 		if err != nil {
 			if errors.Is(err, io.EOF) {
 				break
 			}
-			return fmt.Errorf("ошибка чтения CSV файла: %w", err)
+			cancel(fmt.Errorf("ошибка чтения CSV файла: %w", err))
+			return
 		}
-		advertisement, err := parseCSVRecord(record)
+		line, _ := reader.FieldPos(0)
+		advertisement, err := parseCSVRecord(columns, record)
 		if err != nil {
-			return fmt.Errorf("ошибка парсинга данных: %w", err)
+			cancel(fmt.Errorf("ошибка парсинга данных в строке %d: %w", line, err))
+			return
 		}
-		advertisements = append(advertisements, advertisement)
 
-		if len(advertisements) >= batchSize {
-			tasks <- BatchTask{Advertisements: advertisements}
-			advertisements = advertisements[:0]
+		if len(task.Advertisements) == 0 {
+			task.FirstLine = line
 		}
-	}
+		task.LastLine = line
+		task.Advertisements = append(task.Advertisements, advertisement)
 
-	if len(advertisements) > 0 {
-		tasks <- BatchTask{Advertisements: advertisements}
+		if len(task.Advertisements) >= batchSize && !send() {
+			return
+		}
 	}
 
-	close(tasks)
-	waitGroup.Wait()
-
-	return nil
+	if len(task.Advertisements) > 0 && ctx.Err() == nil {
+		send()
+	}
 }
 
+// saveBatchToDatabase вставляет пакет объявлений и в той же транзакции записывает в outbox
+// события create для всех вставленных строк
 func saveBatchToDatabase(database *internal.Database, batch []model.Advertisement) error {
 	var placeholders []string
 	var args []interface{}
@@ -206,48 +289,35 @@ func saveBatchToDatabase(database *internal.Database, batch []model.Advertisemen
 	}
 	query := fmt.Sprintf(`INSERT INTO advertisements 
         (product_name, description, brand, category, price, currency, stock, ean, color, size, availability) 
-        VALUES %s`, strings.Join(placeholders, ","))
+        VALUES %s
+        RETURNING id, product_name, description, brand, category, price, currency, stock, ean, color, size, availability`,
+		strings.Join(placeholders, ","))
 
-	fmt.Println(query)
-
-	_, err := database.DB.Exec(query, args...)
+	tx, err := database.DB.Beginx()
 	if err != nil {
-		return fmt.Errorf("ошибка вставки данных в БД: %v", err)
+		return fmt.Errorf("ошибка начала транзакции: %w", err)
 	}
+	defer tx.Rollback()
 
-	return nil
-}
-
-func parseCSVRecord(record []string) (model.Advertisement, error) {
-	if len(record) < 3 {
-		return model.Advertisement{}, fmt.Errorf("неверный формат строки: %v", record)
+	// Вставленные строки возвращаются целиком, чтобы события содержали присвоенные id
+	var inserted []model.Advertisement
+	if err := tx.Select(&inserted, query, args...); err != nil {
+		return fmt.Errorf("ошибка вставки данных в БД: %w", err)
 	}
 
-	index, err := strconv.Atoi(record[0])
-	if err != nil {
-		return model.Advertisement{}, fmt.Errorf("неверный индекс: %v", err)
+	events := make([]model.AdvertisementEvent, 0, len(inserted))
+	for i := range inserted {
+		events = append(events, model.AdvertisementEvent{
+			Type: model.AdvertisementCreated, ID: inserted[i].Index, Advertisement: &inserted[i],
+		})
 	}
-	price, err := strconv.ParseFloat(record[5], 64)
-	if err != nil {
-		return model.Advertisement{}, fmt.Errorf("неверная цена: %v", err)
+	if err := repository.WriteOutboxEvents(tx, events); err != nil {
+		return err
 	}
-	stock, err := strconv.Atoi(record[7])
-	if err != nil {
-		return model.Advertisement{}, fmt.Errorf("неверный stock: %v", err)
-	}
-
-	return model.Advertisement{
-		Index:        index,
-		Name:         record[1],
-		Description:  record[2],
-		Brand:        record[3],
-		Category:     record[4],
-		Price:        price,
-		Currency:     record[6],
-		Stock:        stock,
-		Ean:          record[8],
-		Color:        record[9],
-		Size:         record[10],
-		Availability: record[11],
-	}, nil
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("ошибка фиксации транзакции: %w", err)
+	}
+
+	return nil
 }
@@ -2,11 +2,18 @@ package util
 
 import (
 	"SearchService/internal/model"
+	"errors"
 	"reflect"
 	"testing"
 )
 
 func TestParseCSVRecord(t *testing.T) {
+	header := []string{"id", "Name", "Description", "Brand", "Category", "Price", "Currency", "Stock", "Color", "Size", "Availability"}
+	columns, err := newCSVColumns(header, DefaultCSVColumnAliases)
+	if err != nil {
+		t.Fatal(err)
+	}
+
 	tests := []struct {
 		name    string
 		input   []string
@@ -33,7 +40,7 @@ func TestParseCSVRecord(t *testing.T) {
 		},
 		{
 			name:    "invalid index",
-			input:   []string{"notanumber", "Name", "Description"},
+			input:   []string{"notanumber", "Name", "Description", "Brand", "Category", "123.45", "USD", "10", "Red", "L", "In Stock"},
 			wantErr: true,
 		},
 		{
@@ -55,7 +62,7 @@ func TestParseCSVRecord(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := parseCSVRecord(tt.input)
+			got, err := parseCSVRecord(columns, tt.input)
 			if (err != nil) != tt.wantErr {
 				t.Fatalf("ParseCSVRecord() error = %v, wantErr %v", err, tt.wantErr)
 			}
@@ -65,3 +72,84 @@ func TestParseCSVRecord(t *testing.T) {
 		})
 	}
 }
+
+func TestParseCSVRecordReorderedOptionalColumns(t *testing.T) {
+	header := []string{"\ufeffPrice", "barcode", "Product Name", "brand", "CATEGORY", "currency"}
+	columns, err := newCSVColumns(header, DefaultCSVColumnAliases)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := parseCSVRecord(columns, []string{"9.99", "4006381333931", " Pen ", "Stabilo", "Office", "EUR"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := model.Advertisement{Name: "Pen", Brand: "Stabilo", Category: "Office", Price: 9.99, Currency: "EUR", Ean: "4006381333931"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseCSVRecord() = %+v, want %+v", got, want)
+	}
+}
+
+func TestNewCSVColumnsHeaderErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		header []string
+		want   CSVHeaderError
+	}{
+		{
+			name:   "missing required",
+			header: []string{"product_name", "brand", "price"},
+			want:   CSVHeaderError{Missing: []string{"category", "currency"}},
+		},
+		{
+			name:   "unknown column",
+			header: []string{"product_name", "brand", "category", "price", "currency", "weight"},
+			want:   CSVHeaderError{Unknown: []string{`"weight"`}},
+		},
+		{
+			name:   "duplicate column",
+			header: []string{"product_name", "title", "brand", "category", "price", "currency"},
+			want:   CSVHeaderError{Duplicate: []string{"product_name"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newCSVColumns(tt.header, DefaultCSVColumnAliases)
+			var headerErr *CSVHeaderError
+			if !errors.As(err, &headerErr) {
+				t.Fatalf("newCSVColumns() error = %v, want CSVHeaderError", err)
+			}
+			if !reflect.DeepEqual(*headerErr, tt.want) {
+				t.Errorf("newCSVColumns() error = %+v, want %+v", *headerErr, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCSVColumnAliases(t *testing.T) {
+	aliases, err := ParseCSVColumnAliases("product_name:Наименование|Товар, ean:Штрихкод")
+	if err != nil {
+		t.Fatal(err)
+	}
+	columns, err := newCSVColumns([]string{"Товар", "Штрихкод", "brand", "category", "price", "currency"}, aliases)
+	if err != nil {
+		t.Fatalf("newCSVColumns() with custom aliases error = %v", err)
+	}
+	if columns["product_name"] != 0 || columns["ean"] != 1 {
+		t.Errorf("columns = %v, want product_name at 0 and ean at 1", columns)
+	}
+	if len(DefaultCSVColumnAliases["ean"]) != 3 {
+		t.Errorf("ParseCSVColumnAliases() modified DefaultCSVColumnAliases: %v", DefaultCSVColumnAliases["ean"])
+	}
+
+	// "Title" после нормализации совпадает с названием title поля product_name
+	for _, raw := range []string{"weight:Вес", "product_name", "brand:Title", "description:name", "category:Бренд,brand:бренд"} {
+		if _, err := ParseCSVColumnAliases(raw); err == nil {
+			t.Errorf("ParseCSVColumnAliases(%q) error = nil, want error", raw)
+		}
+	}
+	if _, err := ParseCSVColumnAliases("ean:EAN|Штрих-код,ean:штрих код"); err != nil {
+		t.Errorf("ParseCSVColumnAliases() with repeated names of one field error = %v", err)
+	}
+}
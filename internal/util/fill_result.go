@@ -0,0 +1,90 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+	"sync"
+)
+
+// FillStatus — итог загрузки CSV-файла в БД
+type FillStatus string
+
+const (
+	// FillSucceeded — все строки сохранены
+	FillSucceeded FillStatus = "succeeded"
+	// FillPartial — часть пакетов не удалось сохранить, остальные сохранены
+	FillPartial FillStatus = "partial"
+	// FillFailed — загрузка прервана фатальной ошибкой или не сохранено ни одной строки
+	FillFailed FillStatus = "failed"
+)
+
+// FailedBatch — пакет строк, который не удалось сохранить
+type FailedBatch struct {
+	// FirstLine и LastLine — номера строк файла (заголовок — строка 1), с которых начинаются первая и последняя запись пакета
+	FirstLine int
+	LastLine  int
+	Err       error
+}
+
+func (batch FailedBatch) Error() string {
+	return fmt.Sprintf("строки %d-%d: %v", batch.FirstLine, batch.LastLine, batch.Err)
+}
+
+func (batch FailedBatch) Unwrap() error {
+	return batch.Err
+}
+
+// FillResult — сводный результат загрузки CSV-файла
+type FillResult struct {
+	RowsSaved     int
+	RowsFailed    int
+	FailedBatches []FailedBatch
+	// Err — фатальная ошибка, остановившая загрузку (чтение файла, парсинг, недоступная БД, отмена)
+	Err error
+}
+
+// Status различает полный успех, частичный успех и неудачу
+func (result FillResult) Status() FillStatus {
+	switch {
+	case result.Err != nil:
+		return FillFailed
+	case len(result.FailedBatches) == 0:
+		return FillSucceeded
+	case result.RowsSaved > 0:
+		return FillPartial
+	default:
+		return FillFailed
+	}
+}
+
+// fillCollector собирает результаты пакетов, сохраняемых воркерами конкурентно
+type fillCollector struct {
+	mu     sync.Mutex
+	result FillResult
+}
+
+func (collector *fillCollector) add(task BatchTask, err error) {
+	collector.mu.Lock()
+	defer collector.mu.Unlock()
+
+	if err != nil {
+		collector.result.RowsFailed += len(task.Advertisements)
+		collector.result.FailedBatches = append(collector.result.FailedBatches,
+			FailedBatch{FirstLine: task.FirstLine, LastLine: task.LastLine, Err: err})
+		return
+	}
+	collector.result.RowsSaved += len(task.Advertisements)
+}
+
+// isFatalBatchError сообщает, помешает ли ошибка сохранения пакета и остальным пакетам (нет соединения
+// с БД, нет таблицы и т.п.). Ошибки данных (класс 22) и нарушения ограничений (класс 23) касаются только
+// строк этого пакета, поэтому загрузка остальных продолжается.
+func isFatalBatchError(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		class := pqErr.Code.Class()
+		return class != "22" && class != "23"
+	}
+	return true
+}
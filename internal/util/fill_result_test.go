@@ -0,0 +1,120 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+	"strings"
+	"testing"
+)
+
+func TestFillResultStatus(t *testing.T) {
+	failed := []FailedBatch{{FirstLine: 2, LastLine: 101, Err: errors.New("duplicate key")}}
+	tests := []struct {
+		name   string
+		result FillResult
+		want   FillStatus
+	}{
+		{name: "all saved", result: FillResult{RowsSaved: 200}, want: FillSucceeded},
+		{name: "some batches failed", result: FillResult{RowsSaved: 100, RowsFailed: 100, FailedBatches: failed}, want: FillPartial},
+		{name: "every batch failed", result: FillResult{RowsFailed: 100, FailedBatches: failed}, want: FillFailed},
+		{name: "fatal error", result: FillResult{RowsSaved: 100, Err: errors.New("connection refused")}, want: FillFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.result.Status(); got != tt.want {
+				t.Errorf("Status() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsFatalBatchError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "unique violation", err: fmt.Errorf("ошибка вставки: %w", &pq.Error{Code: "23505"}), want: false},
+		{name: "numeric out of range", err: &pq.Error{Code: "22003"}, want: false},
+		{name: "undefined table", err: &pq.Error{Code: "42P01"}, want: true},
+		{name: "connection error", err: errors.New("dial tcp: connection refused"), want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isFatalBatchError(tt.err); got != tt.want {
+				t.Errorf("isFatalBatchError() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadCSVBatches(t *testing.T) {
+	header := "id,product_name,description,brand,category,price,currency,stock,ean,color,size,availability\n"
+	row := func(id int) string {
+		return fmt.Sprintf("%d,Name,Description,Brand,Category,1.5,USD,3,,Red,L,in_stock\n", id)
+	}
+
+	t.Run("batches with line ranges", func(t *testing.T) {
+		file := header
+		for i := 1; i <= 5; i++ {
+			file += row(i)
+		}
+
+		ctx, cancel := context.WithCancelCause(context.Background())
+		defer cancel(nil)
+		tasks := make(chan BatchTask, 10)
+		readCSVBatches(ctx, strings.NewReader(file), 2, DefaultCSVColumnAliases, tasks, cancel)
+		close(tasks)
+
+		var lines [][2]int
+		for task := range tasks {
+			lines = append(lines, [2]int{task.FirstLine, task.LastLine})
+		}
+		want := [][2]int{{2, 3}, {4, 5}, {6, 6}}
+		if fmt.Sprint(lines) != fmt.Sprint(want) {
+			t.Errorf("batch lines = %v, want %v", lines, want)
+		}
+		if err := context.Cause(ctx); err != nil {
+			t.Errorf("unexpected cancel cause: %v", err)
+		}
+	})
+
+	t.Run("parse error cancels", func(t *testing.T) {
+		file := header + row(1) + strings.Replace(row(2), "1.5", "notafloat", 1) + row(3)
+
+		ctx, cancel := context.WithCancelCause(context.Background())
+		defer cancel(nil)
+		tasks := make(chan BatchTask, 10)
+		readCSVBatches(ctx, strings.NewReader(file), 10, DefaultCSVColumnAliases, tasks, cancel)
+		close(tasks)
+
+		if err := context.Cause(ctx); err == nil || !strings.Contains(err.Error(), "строке 3") {
+			t.Errorf("cancel cause = %v, want parse error in line 3", err)
+		}
+		if len(tasks) != 0 {
+			t.Errorf("%d batches sent after parse error, want 0", len(tasks))
+		}
+	})
+
+	t.Run("header error before any batch", func(t *testing.T) {
+		file := "product_name,brand,price\n" + "Pen,Stabilo,1.5\n"
+
+		ctx, cancel := context.WithCancelCause(context.Background())
+		defer cancel(nil)
+		tasks := make(chan BatchTask, 10)
+		readCSVBatches(ctx, strings.NewReader(file), 1, DefaultCSVColumnAliases, tasks, cancel)
+		close(tasks)
+
+		var headerErr *CSVHeaderError
+		if !errors.As(context.Cause(ctx), &headerErr) {
+			t.Errorf("cancel cause = %v, want CSVHeaderError", context.Cause(ctx))
+		}
+		if len(tasks) != 0 {
+			t.Errorf("%d batches sent with invalid header, want 0", len(tasks))
+		}
+	})
+}
@@ -0,0 +1,162 @@
+package util
+
+// incremental_sync содержит инкрементальную синхронизацию: из БД выбираются только строки, изменённые
+// после сохранённой отметки (updated_at, id), и индексируются в advertisements. Отметка сдвигается
+// после каждого успешно проиндексированного пакета, поэтому прерванная синхронизация продолжается
+// с последнего подтверждённого пакета, а повторно проиндексированные строки ничего не портят.
+// Мягкое удаление тоже обновляет updated_at, поэтому удалённые строки попадают в выборку
+// и превращаются в удаление документа из индекса.
+
+import (
+	"SearchService/internal/model"
+	"SearchService/internal/ports"
+	"context"
+	"fmt"
+	"github.com/elastic/go-elasticsearch/v8"
+	"log"
+	"time"
+)
+
+// AdvertisementsWatermark — имя отметки синхронизации индекса объявлений
+const AdvertisementsWatermark = "advertisements"
+
+// SyncOptions — настройки инкрементальной синхронизации
+type SyncOptions struct {
+	// FetchSize — количество изменённых строк, загружаемых и индексируемых за один пакет
+	FetchSize int
+	// Lag — строки, изменённые позже now() - Lag по часам БД, откладываются до следующего запуска.
+	// updated_at выставляется в начале транзакции, поэтому транзакция, зафиксированная позже
+	// более новой, иначе могла бы оказаться за уже сдвинутой отметкой и быть пропущена.
+	Lag time.Duration
+	// RetryPolicy — повтор временно отклонённых документов
+	RetryPolicy BulkRetryPolicy
+}
+
+// DefaultSyncOptions используются для полей SyncOptions, оставленных нулевыми
+var DefaultSyncOptions = SyncOptions{
+	FetchSize:   1000,
+	Lag:         30 * time.Second,
+	RetryPolicy: DefaultBulkRetryPolicy,
+}
+
+func (options SyncOptions) withDefaults() SyncOptions {
+	if options.FetchSize <= 0 {
+		options.FetchSize = DefaultSyncOptions.FetchSize
+	}
+	if options.Lag <= 0 {
+		options.Lag = DefaultSyncOptions.Lag
+	}
+	if options.RetryPolicy == (BulkRetryPolicy{}) {
+		options.RetryPolicy = DefaultSyncOptions.RetryPolicy
+	}
+	return options
+}
+
+// SyncChangedAdvertisements индексирует объявления, изменённые после сохранённой отметки, и сдвигает её.
+// Если документ не удалось проиндексировать, синхронизация останавливается, не сдвигая отметку
+// за неудачный пакет. Отмена ctx прерывает работу между пакетами.
+func SyncChangedAdvertisements(ctx context.Context, esClient *elasticsearch.Client, loader ports.AdvertisementChangeLoader,
+	watermarks ports.SyncWatermarkStore, options SyncOptions) (IndexingReport, error) {
+	options = options.withDefaults()
+
+	var report IndexingReport
+	watermark, _, err := watermarks.GetWatermark(AdvertisementsWatermark)
+	if err != nil {
+		return report, err
+	}
+
+	for ctx.Err() == nil {
+		changes, err := loader.GetAdvertisementsChangedSince(watermark, options.Lag, options.FetchSize)
+		if err != nil {
+			return report, err
+		}
+		if len(changes) == 0 {
+			break
+		}
+
+		actions, err := changeActions(AdvertisementsIndex, changes)
+		if err != nil {
+			return report, err
+		}
+
+		batchReport, err := executeBulk(esClient, actions, options.RetryPolicy)
+		report.add(batchReport)
+		if err != nil {
+			return report, fmt.Errorf("ошибка вставки: %w", err)
+		}
+		if batchReport.Failed > 0 {
+			return report, fmt.Errorf("не удалось проиндексировать %d документов, отметка синхронизации не сдвинута", batchReport.Failed)
+		}
+
+		watermark = changes[len(changes)-1].Watermark()
+		if err := watermarks.SaveWatermark(AdvertisementsWatermark, watermark); err != nil {
+			return report, err
+		}
+		log.Printf("Синхронизировано: проиндексировано %d, удалено %d, отметка %s / id %d",
+			report.Indexed, report.Deleted, watermark.UpdatedAt.Format(time.RFC3339Nano), watermark.ID)
+
+		if len(changes) < options.FetchSize {
+			break
+		}
+	}
+
+	return report, nil
+}
+
+// catchUpChanges индексирует в index все изменения после since, зафиксированные к моменту чтения,
+// и возвращает отметку последнего из них. Используется переиндексацией, чтобы перенести в новое
+// поколение записи, внесённые после того, как конвейер прочитал соответствующие строки.
+func catchUpChanges(esClient *elasticsearch.Client, loader ports.AdvertisementChangeLoader, index string,
+	since model.SyncWatermark, options SyncOptions) (model.SyncWatermark, IndexingReport, error) {
+	var report IndexingReport
+	watermark := since
+	for {
+		// Нулевое отставание: догоняющий проход читает всё зафиксированное к этому моменту
+		changes, err := loader.GetAdvertisementsChangedSince(watermark, 0, options.FetchSize)
+		if err != nil {
+			return watermark, report, err
+		}
+		if len(changes) == 0 {
+			return watermark, report, nil
+		}
+
+		actions, err := changeActions(index, changes)
+		if err != nil {
+			return watermark, report, err
+		}
+
+		batchReport, err := executeBulk(esClient, actions, options.RetryPolicy)
+		report.add(batchReport)
+		if err != nil {
+			return watermark, report, fmt.Errorf("ошибка вставки: %w", err)
+		}
+		if batchReport.Failed > 0 {
+			return watermark, report, fmt.Errorf("не удалось проиндексировать %d изменённых документов", batchReport.Failed)
+		}
+
+		watermark = changes[len(changes)-1].Watermark()
+		if len(changes) < options.FetchSize {
+			return watermark, report, nil
+		}
+	}
+}
+
+// changeActions превращает изменённые строки в bulk-операции над index: мягко удалённые — в удаление документа
+func changeActions(index string, changes []model.AdvertisementChange) ([]bulkAction, error) {
+	actions := make([]bulkAction, 0, len(changes))
+	for i := range changes {
+		var action bulkAction
+		var err error
+		if changes[i].DeletedAt != nil {
+			action, err = newDeleteAction(index, fmt.Sprint(changes[i].Index))
+		} else {
+			action, err = newIndexAction(index, fmt.Sprint(changes[i].Index), advertisementDocument(&changes[i].Advertisement))
+		}
+		if err != nil {
+			return nil, err
+		}
+		actions = append(actions, action)
+	}
+
+	return actions, nil
+}
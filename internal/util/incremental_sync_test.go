@@ -0,0 +1,177 @@
+package util
+
+import (
+	"SearchService/internal/model"
+	"context"
+	"github.com/elastic/go-elasticsearch/v8"
+	"sort"
+	"testing"
+	"time"
+)
+
+// changeLoader — AdvertisementChangeLoader поверх среза изменений в памяти; часы БД — time.Now
+type changeLoader struct {
+	changes []model.AdvertisementChange
+}
+
+func (loader *changeLoader) GetAdvertisementsChangedSince(after model.SyncWatermark, lag time.Duration, limit int) ([]model.AdvertisementChange, error) {
+	before := time.Now().Add(-lag)
+	sorted := append([]model.AdvertisementChange(nil), loader.changes...)
+	sort.Slice(sorted, func(i, j int) bool {
+		if !sorted[i].UpdatedAt.Equal(sorted[j].UpdatedAt) {
+			return sorted[i].UpdatedAt.Before(sorted[j].UpdatedAt)
+		}
+		return sorted[i].Index < sorted[j].Index
+	})
+
+	var page []model.AdvertisementChange
+	for _, change := range sorted {
+		newer := change.UpdatedAt.After(after.UpdatedAt) || (change.UpdatedAt.Equal(after.UpdatedAt) && change.Index > after.ID)
+		if newer && !change.UpdatedAt.After(before) && len(page) < limit {
+			page = append(page, change)
+		}
+	}
+	return page, nil
+}
+
+func (loader *changeLoader) GetDatabaseTime() (time.Time, error) {
+	return time.Now(), nil
+}
+
+// memoryWatermarks — SyncWatermarkStore в памяти
+type memoryWatermarks map[string]model.SyncWatermark
+
+func (store memoryWatermarks) GetWatermark(name string) (model.SyncWatermark, bool, error) {
+	watermark, found := store[name]
+	return watermark, found, nil
+}
+
+func (store memoryWatermarks) SaveWatermark(name string, watermark model.SyncWatermark) error {
+	store[name] = watermark
+	return nil
+}
+
+func TestSyncChangedAdvertisements(t *testing.T) {
+	start := time.Now().Add(-time.Hour)
+	loader := &changeLoader{}
+	for i := 1; i <= 25; i++ {
+		// По несколько строк с одинаковым updated_at, как при пакетной вставке в одной транзакции
+		loader.changes = append(loader.changes, model.AdvertisementChange{
+			Advertisement: model.Advertisement{Index: i},
+			UpdatedAt:     start.Add(time.Duration(i/4) * time.Second),
+		})
+	}
+	// Изменение моложе Lag откладывается до следующего запуска
+	loader.changes = append(loader.changes, model.AdvertisementChange{Advertisement: model.Advertisement{Index: 26}, UpdatedAt: time.Now()})
+
+	transport := &bulkTransport{ids: make(map[string]int)}
+	esClient, err := elasticsearch.NewClient(elasticsearch.Config{Transport: transport})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	watermarks := memoryWatermarks{}
+	options := SyncOptions{FetchSize: 10, Lag: time.Minute}
+
+	report, err := SyncChangedAdvertisements(context.Background(), esClient, loader, watermarks, options)
+	if err != nil {
+		t.Fatalf("SyncChangedAdvertisements() error = %v", err)
+	}
+	if report.Indexed != 25 || len(transport.ids) != 25 {
+		t.Fatalf("first sync indexed %d (%d distinct), want 25", report.Indexed, len(transport.ids))
+	}
+	if got := watermarks[AdvertisementsWatermark]; got.ID != 25 {
+		t.Errorf("watermark = %+v, want id 25", got)
+	}
+
+	report, err = SyncChangedAdvertisements(context.Background(), esClient, loader, watermarks, options)
+	if err != nil || report.Indexed != 0 {
+		t.Fatalf("second sync indexed %d, err %v; want nothing", report.Indexed, err)
+	}
+
+	loader.changes[4].UpdatedAt = start.Add(time.Minute)
+	report, err = SyncChangedAdvertisements(context.Background(), esClient, loader, watermarks, options)
+	if err != nil || report.Indexed != 1 {
+		t.Fatalf("sync after update indexed %d, err %v; want 1", report.Indexed, err)
+	}
+	if transport.ids["5"] != 2 {
+		t.Errorf("document 5 sent %d times, want 2", transport.ids["5"])
+	}
+
+	// Мягкое удаление сдвигает updated_at и превращается в удаление документа
+	deletedAt := start.Add(2 * time.Minute)
+	loader.changes[6].UpdatedAt = deletedAt
+	loader.changes[6].DeletedAt = &deletedAt
+	report, err = SyncChangedAdvertisements(context.Background(), esClient, loader, watermarks, options)
+	if err != nil || report.Indexed != 0 || report.Deleted != 1 {
+		t.Fatalf("sync after soft delete = %+v, err %v; want 1 deleted", report, err)
+	}
+	if transport.deleted["7"] != 1 {
+		t.Errorf("document 7 deleted %d times, want 1", transport.deleted["7"])
+	}
+}
+
+func TestSyncChangedAdvertisementsKeepsWatermarkOnFailure(t *testing.T) {
+	start := time.Now().Add(-time.Hour)
+	loader := &changeLoader{}
+	for i := 1; i <= 5; i++ {
+		loader.changes = append(loader.changes, model.AdvertisementChange{
+			Advertisement: model.Advertisement{Index: i},
+			UpdatedAt:     start.Add(time.Duration(i) * time.Second),
+		})
+	}
+
+	transport := &bulkTransport{ids: make(map[string]int), rejected: map[string]bool{"3": true}}
+	esClient, err := elasticsearch.NewClient(elasticsearch.Config{Transport: transport})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stored := model.SyncWatermark{UpdatedAt: start, ID: 0}
+	watermarks := memoryWatermarks{AdvertisementsWatermark: stored}
+
+	report, err := SyncChangedAdvertisements(context.Background(), esClient, loader, watermarks, SyncOptions{FetchSize: 10, Lag: time.Minute})
+	if err == nil {
+		t.Fatal("SyncChangedAdvertisements() error = nil, want failure for rejected document")
+	}
+	if report.Failed != 1 {
+		t.Errorf("report.Failed = %d, want 1", report.Failed)
+	}
+	if got := watermarks[AdvertisementsWatermark]; got != stored {
+		t.Errorf("watermark = %+v, want unchanged %+v", got, stored)
+	}
+}
+
+func TestCatchUpChanges(t *testing.T) {
+	start := time.Now().Add(-time.Hour)
+	deletedAt := start.Add(3 * time.Second)
+	loader := &changeLoader{changes: []model.AdvertisementChange{
+		{Advertisement: model.Advertisement{Index: 1}, UpdatedAt: start.Add(-time.Second)},
+		{Advertisement: model.Advertisement{Index: 2}, UpdatedAt: start.Add(time.Second)},
+		{Advertisement: model.Advertisement{Index: 3}, UpdatedAt: start.Add(2 * time.Second)},
+		{Advertisement: model.Advertisement{Index: 4}, UpdatedAt: deletedAt, DeletedAt: &deletedAt},
+		// Догоняющий проход не откладывает свежие изменения, в отличие от инкрементальной синхронизации
+		{Advertisement: model.Advertisement{Index: 5}, UpdatedAt: time.Now()},
+	}}
+
+	transport := &bulkTransport{ids: make(map[string]int)}
+	esClient, err := elasticsearch.NewClient(elasticsearch.Config{Transport: transport})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	options := SyncOptions{FetchSize: 2}.withDefaults()
+	watermark, report, err := catchUpChanges(esClient, loader, "advertisements_v2", model.SyncWatermark{UpdatedAt: start}, options)
+	if err != nil {
+		t.Fatalf("catchUpChanges() error = %v", err)
+	}
+	if report.Indexed != 3 || report.Deleted != 1 {
+		t.Errorf("catchUpChanges() report = %+v, want 3 indexed and 1 deleted", report)
+	}
+	if transport.ids["1"] != 0 {
+		t.Error("change before since must not be replayed")
+	}
+	if watermark.ID != 5 {
+		t.Errorf("watermark = %+v, want id 5", watermark)
+	}
+}
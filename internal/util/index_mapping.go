@@ -0,0 +1,199 @@
+package util
+
+// index_mapping содержит управляемый сервисом маппинг индекса объявлений:
+// создание индекса из mappings/advertisements.json и проверку живого маппинга на расхождения
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"github.com/elastic/go-elasticsearch/v8"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+//go:embed mappings/advertisements.json
+var advertisementsIndexDefinition []byte
+
+// comparedMappingAttributes — атрибуты поля, расхождение которых считается дрейфом маппинга
+var comparedMappingAttributes = []string{"type", "analyzer", "search_analyzer", "normalizer", "scaling_factor"}
+
+// AdvertisementsMappingVersion возвращает версию маппинга из _meta.mapping_version
+func AdvertisementsMappingVersion() (int, error) {
+	var definition struct {
+		Mappings struct {
+			Meta struct {
+				MappingVersion int `json:"mapping_version"`
+			} `json:"_meta"`
+		} `json:"mappings"`
+	}
+	if err := json.Unmarshal(advertisementsIndexDefinition, &definition); err != nil {
+		return 0, fmt.Errorf("ошибка чтения маппинга индекса: %w", err)
+	}
+
+	return definition.Mappings.Meta.MappingVersion, nil
+}
+
+// EnsureAdvertisementsIndex создаёт первое поколение индекса объявлений из управляемого маппинга
+// и направляет на него алиас advertisements, если ни алиаса, ни индекса ещё нет.
+// Если индекс уже существует, его маппинг сверяется с ожидаемым и любое расхождение возвращается ошибкой.
+func EnsureAdvertisementsIndex(esClient *elasticsearch.Client) error {
+	exists, err := esClient.Indices.Exists([]string{AdvertisementsIndex})
+	if err != nil {
+		return fmt.Errorf("ошибка проверки индекса: %w", err)
+	}
+	exists.Body.Close()
+
+	if exists.StatusCode == http.StatusNotFound {
+		generations, err := listGenerations(esClient)
+		if err != nil {
+			return err
+		}
+
+		generation := 1
+		if len(generations) > 0 {
+			generation = generations[len(generations)-1] + 1
+		}
+
+		index := GenerationIndexName(generation)
+		if err := CreateAdvertisementsIndex(esClient, index); err != nil {
+			return err
+		}
+		_, err = swapAlias(esClient, index)
+		return err
+	}
+
+	return VerifyAdvertisementsMapping(esClient, AdvertisementsIndex)
+}
+
+// CreateAdvertisementsIndex создаёт индекс с заданным именем из управляемых настроек и маппинга
+func CreateAdvertisementsIndex(esClient *elasticsearch.Client, index string) error {
+	response, err := esClient.Indices.Create(
+		index,
+		esClient.Indices.Create.WithBody(bytes.NewReader(advertisementsIndexDefinition)),
+	)
+	if err != nil {
+		return fmt.Errorf("ошибка создания индекса %s: %w", index, err)
+	}
+	defer response.Body.Close()
+
+	if response.IsError() {
+		return fmt.Errorf("ошибка создания индекса %s: %s", index, response.String())
+	}
+
+	return nil
+}
+
+// VerifyAdvertisementsMapping сравнивает живой маппинг индекса (или алиаса) с управляемым
+func VerifyAdvertisementsMapping(esClient *elasticsearch.Client, index string) error {
+	response, err := esClient.Indices.GetMapping(esClient.Indices.GetMapping.WithIndex(index))
+	if err != nil {
+		return fmt.Errorf("ошибка получения маппинга индекса %s: %w", index, err)
+	}
+	defer response.Body.Close()
+
+	if response.IsError() {
+		return fmt.Errorf("ошибка получения маппинга индекса %s: %s", index, response.String())
+	}
+
+	var live map[string]struct {
+		Mappings map[string]interface{} `json:"mappings"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&live); err != nil {
+		return fmt.Errorf("ошибка парсинга маппинга индекса %s: %w", index, err)
+	}
+
+	var expected struct {
+		Mappings map[string]interface{} `json:"mappings"`
+	}
+	if err := json.Unmarshal(advertisementsIndexDefinition, &expected); err != nil {
+		return fmt.Errorf("ошибка чтения маппинга индекса: %w", err)
+	}
+
+	for concreteIndex, mapping := range live {
+		if drift := diffMappings(expected.Mappings, mapping.Mappings); len(drift) > 0 {
+			return fmt.Errorf("маппинг индекса %s расходится с ожидаемым:\n  %s", concreteIndex, strings.Join(drift, "\n  "))
+		}
+	}
+
+	return nil
+}
+
+// diffMappings возвращает список расхождений живого маппинга с ожидаемым
+func diffMappings(expected map[string]interface{}, live map[string]interface{}) []string {
+	var drift []string
+
+	expectedVersion := nestedValue(expected, "_meta", "mapping_version")
+	liveVersion := nestedValue(live, "_meta", "mapping_version")
+	if !reflect.DeepEqual(expectedVersion, liveVersion) {
+		drift = append(drift, fmt.Sprintf("_meta.mapping_version: ожидается %v, в индексе %v", expectedVersion, liveVersion))
+	}
+
+	if expectedDynamic, liveDynamic := fmt.Sprint(expected["dynamic"]), fmt.Sprint(live["dynamic"]); expectedDynamic != liveDynamic {
+		drift = append(drift, fmt.Sprintf("dynamic: ожидается %s, в индексе %s", expectedDynamic, liveDynamic))
+	}
+
+	expectedFields := flattenMappingFields(expected, "")
+	liveFields := flattenMappingFields(live, "")
+
+	for path, expectedField := range expectedFields {
+		liveField, ok := liveFields[path]
+		if !ok {
+			drift = append(drift, fmt.Sprintf("%s: поле отсутствует в индексе", path))
+			continue
+		}
+
+		for _, attribute := range comparedMappingAttributes {
+			if !reflect.DeepEqual(expectedField[attribute], liveField[attribute]) {
+				drift = append(drift, fmt.Sprintf("%s.%s: ожидается %v, в индексе %v", path, attribute, expectedField[attribute], liveField[attribute]))
+			}
+		}
+	}
+
+	for path := range liveFields {
+		if _, ok := expectedFields[path]; !ok {
+			drift = append(drift, fmt.Sprintf("%s: поле не описано в управляемом маппинге", path))
+		}
+	}
+
+	sort.Strings(drift)
+	return drift
+}
+
+// flattenMappingFields раскладывает properties и подполя fields в плоскую карту путь -> описание поля
+func flattenMappingFields(mapping map[string]interface{}, prefix string) map[string]map[string]interface{} {
+	fields := make(map[string]map[string]interface{})
+
+	for _, key := range []string{"properties", "fields"} {
+		children, _ := mapping[key].(map[string]interface{})
+		for name, child := range children {
+			field, ok := child.(map[string]interface{})
+			if !ok {
+				continue
+			}
+
+			path := prefix + name
+			fields[path] = field
+			for childPath, childField := range flattenMappingFields(field, path+".") {
+				fields[childPath] = childField
+			}
+		}
+	}
+
+	return fields
+}
+
+func nestedValue(mapping map[string]interface{}, keys ...string) interface{} {
+	var current interface{} = mapping
+	for _, key := range keys {
+		object, ok := current.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		current = object[key]
+	}
+	return current
+}
@@ -0,0 +1,45 @@
+package util
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestDiffMappings(t *testing.T) {
+	var definition struct {
+		Mappings map[string]interface{} `json:"mappings"`
+	}
+	if err := json.Unmarshal(advertisementsIndexDefinition, &definition); err != nil {
+		t.Fatal(err)
+	}
+
+	if drift := diffMappings(definition.Mappings, definition.Mappings); len(drift) != 0 {
+		t.Fatalf("diffMappings() for identical mappings = %v, want none", drift)
+	}
+
+	// Маппинг, который Elasticsearch выводит динамически при первой индексации
+	var dynamic map[string]interface{}
+	raw := `{
+		"properties": {
+			"availability": {"type": "text", "fields": {"keyword": {"type": "keyword", "ignore_above": 256}}},
+			"price": {"type": "float"}
+		}
+	}`
+	if err := json.Unmarshal([]byte(raw), &dynamic); err != nil {
+		t.Fatal(err)
+	}
+
+	drift := strings.Join(diffMappings(definition.Mappings, dynamic), "\n")
+	for _, want := range []string{
+		"_meta.mapping_version",
+		"availability.type",
+		"availability.keyword: поле не описано",
+		"price.scaling_factor",
+		"ean: поле отсутствует",
+	} {
+		if !strings.Contains(drift, want) {
+			t.Errorf("diffMappings() drift does not mention %q:\n%s", want, drift)
+		}
+	}
+}
@@ -9,113 +9,70 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/elastic/go-elasticsearch/v8"
-	"log"
+	"net/http"
+	"strconv"
 )
 
-func indexAdvertisement(esClient *elasticsearch.Client, advertisement *model.Advertisement) error {
-	body := map[string]interface{}{
-		"id":           advertisement.Index,
-		"product_name": advertisement.Name,
-		"description":  advertisement.Description,
-		"brand":        advertisement.Brand,
-		"category":     advertisement.Category,
-		"price":        advertisement.Price,
-		"currency":     advertisement.Currency,
-		"stock":        advertisement.Stock,
-		"ean":          advertisement.Ean,
-		"color":        advertisement.Color,
-		"size":         advertisement.Size,
-		"availability": advertisement.Availability,
+// AdvertisementsIndex — имя индекса объявлений в Elasticsearch. Маппинг описан в mappings/advertisements.json.
+const AdvertisementsIndex = "advertisements"
+
+// advertisementDocument формирует документ Elasticsearch из объявления.
+// Поля name_suggest и brand_suggest дублируют название и бренд для автодополнения.
+func advertisementDocument(advertisement *model.Advertisement) map[string]interface{} {
+	return map[string]interface{}{
+		"id":            advertisement.Index,
+		"product_name":  advertisement.Name,
+		"description":   advertisement.Description,
+		"brand":         advertisement.Brand,
+		"category":      advertisement.Category,
+		"price":         advertisement.Price,
+		"currency":      advertisement.Currency,
+		"stock":         advertisement.Stock,
+		"ean":           advertisement.Ean,
+		"color":         advertisement.Color,
+		"size":          advertisement.Size,
+		"availability":  advertisement.Availability,
+		"name_suggest":  advertisement.Name,
+		"brand_suggest": advertisement.Brand,
 	}
+}
 
-	jsonBody, _ := json.Marshal(body)
+// indexAdvertisement записывает объявление в индекс advertisements.
+// refresh = true делает документ видимым в поиске сразу после ответа.
+func indexAdvertisement(esClient *elasticsearch.Client, advertisement *model.Advertisement, refresh bool) error {
+	jsonBody, err := json.Marshal(advertisementDocument(advertisement))
+	if err != nil {
+		return fmt.Errorf("ошибка сериализации объявления %d: %w", advertisement.Index, err)
+	}
 
-	_, err := esClient.Index(
-		"advertisements", // имя индекса
+	response, err := esClient.Index(
+		AdvertisementsIndex,
 		bytes.NewReader(jsonBody),
 		esClient.Index.WithDocumentID(fmt.Sprint(advertisement.Index)),
-		esClient.Index.WithRefresh("true"), // чтобы сразу видеть в поиске
+		esClient.Index.WithRefresh(strconv.FormatBool(refresh)),
 	)
 	if err != nil {
 		return fmt.Errorf("ошибка индексирования: %w", err)
 	}
-
-	return nil
-}
-
-func bulkIndexAdvertisements(esClient *elasticsearch.Client, advertisements []model.Advertisement) error {
-	var buffer bytes.Buffer
-
-	for _, advertisement := range advertisements {
-		// Заголовок операции bulk
-		meta := map[string]map[string]string{
-			"index": {
-				"_index": "advertisements",
-				"_id":    fmt.Sprint(advertisement.Index),
-			},
-		}
-		metaJson, _ := json.Marshal(meta)
-		buffer.Write(metaJson)
-		buffer.WriteByte('\n')
-
-		document := map[string]interface{}{
-			"id":           advertisement.Index,
-			"product_name": advertisement.Name,
-			"description":  advertisement.Description,
-			"brand":        advertisement.Brand,
-			"category":     advertisement.Category,
-			"price":        advertisement.Price,
-			"currency":     advertisement.Currency,
-			"stock":        advertisement.Stock,
-			"ean":          advertisement.Ean,
-			"color":        advertisement.Color,
-			"size":         advertisement.Size,
-			"availability": advertisement.Availability,
-		}
-		documentJson, _ := json.Marshal(document)
-		buffer.Write(documentJson)
-		buffer.WriteByte('\n')
-	}
-
-	response, err := esClient.Bulk(bytes.NewReader(buffer.Bytes()))
-	if err != nil {
-		return fmt.Errorf("ошибка bulk вставки: %w", err)
-	}
 	defer response.Body.Close()
 
 	if response.IsError() {
-		return fmt.Errorf("ошибка Bulk API: %v", response.String())
+		return fmt.Errorf("ошибка индексирования объявления %d: %s", advertisement.Index, response.String())
 	}
 
-	log.Println("Все объявления успешно проиндексированы")
 	return nil
 }
 
-func MigrationAllAdvertisements(esClient *elasticsearch.Client, loader ports.AdvertisementBatchLoader) error {
-	limit := 1000
-	offset := 0
-
-	for {
-		advertisements, err := loader.GetAdvertisementsBatch(limit, offset)
-		if err != nil {
-			return fmt.Errorf("ошибка получения объявлений: %w", err)
-		}
-		if len(advertisements) == 0 {
-			break
-		}
-
-		log.Printf("Загружено %d объявлений", len(advertisements))
-
-		err = bulkIndexAdvertisements(esClient, advertisements)
-		if err != nil {
-			return fmt.Errorf("ошибка вставки: %v", err)
-		}
-
-		if len(advertisements) < limit {
-			break
-		}
+// deleteAdvertisement удаляет объявление из индекса advertisements. Отсутствие документа ошибкой не считается.
+func deleteAdvertisement(esClient *elasticsearch.Client, id int) error {
+	response, err := esClient.Delete(AdvertisementsIndex, fmt.Sprint(id))
+	if err != nil {
+		return fmt.Errorf("ошибка удаления из индекса: %w", err)
+	}
+	defer response.Body.Close()
 
-		offset += limit
+	if response.IsError() && response.StatusCode != http.StatusNotFound {
+		return fmt.Errorf("ошибка удаления объявления %d из индекса: %s", id, response.String())
 	}
 
 	return nil
@@ -127,10 +84,27 @@ func MigrationAdvertisement(esClient *elasticsearch.Client, loader ports.Adverti
 		return fmt.Errorf("ошибка получения объявления: %v", err)
 	}
 
-	err = indexAdvertisement(esClient, &advertisement)
+	err = indexAdvertisement(esClient, &advertisement, true) // чтобы сразу видеть в поиске
 	if err != nil {
 		return fmt.Errorf("ошибка вставки: %v", err)
 	}
 
 	return nil
 }
+
+// ElasticIndexer синхронизирует отдельные объявления с индексом advertisements
+type ElasticIndexer struct {
+	esClient *elasticsearch.Client
+}
+
+func NewElasticIndexer(esClient *elasticsearch.Client) *ElasticIndexer {
+	return &ElasticIndexer{esClient: esClient}
+}
+
+func (indexer *ElasticIndexer) IndexAdvertisement(advertisement *model.Advertisement) error {
+	return indexAdvertisement(indexer.esClient, advertisement, false)
+}
+
+func (indexer *ElasticIndexer) DeleteAdvertisement(id int) error {
+	return deleteAdvertisement(indexer.esClient, id)
+}
@@ -0,0 +1,246 @@
+package util
+
+// indexing_pipeline содержит конвейер полной индексации: один читатель выгружает объявления
+// из AdvertisementBatchLoader и собирает bulk-запросы, ограниченные по числу документов и размеру,
+// а несколько воркеров параллельно отправляют их в Elasticsearch. Очередь между ними ограничена,
+// поэтому при медленном Elasticsearch чтение из БД приостанавливается.
+
+import (
+	"SearchService/internal/ports"
+	"context"
+	"fmt"
+	"github.com/elastic/go-elasticsearch/v8"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// PipelineOptions — настройки конвейера индексации
+type PipelineOptions struct {
+	// Workers — количество параллельных отправителей bulk-запросов
+	Workers int
+	// FetchSize — количество строк, загружаемых из БД за один запрос
+	FetchSize int
+	// FlushDocuments — максимальное количество документов в одном bulk-запросе
+	FlushDocuments int
+	// FlushBytes — максимальный размер тела bulk-запроса в байтах
+	FlushBytes int
+	// QueueSize — сколько собранных bulk-запросов может ждать отправки
+	QueueSize int
+	// ProgressInterval — период вывода прогресса в лог
+	ProgressInterval time.Duration
+	// RetryPolicy — повтор временно отклонённых документов
+	RetryPolicy BulkRetryPolicy
+}
+
+// DefaultPipelineOptions используются для полей PipelineOptions, оставленных нулевыми
+var DefaultPipelineOptions = PipelineOptions{
+	Workers:          4,
+	FetchSize:        1000,
+	FlushDocuments:   1000,
+	FlushBytes:       5 << 20,
+	QueueSize:        8,
+	ProgressInterval: 10 * time.Second,
+	RetryPolicy:      DefaultBulkRetryPolicy,
+}
+
+func (options PipelineOptions) withDefaults() PipelineOptions {
+	if options.Workers <= 0 {
+		options.Workers = DefaultPipelineOptions.Workers
+	}
+	if options.FetchSize <= 0 {
+		options.FetchSize = DefaultPipelineOptions.FetchSize
+	}
+	if options.FlushDocuments <= 0 {
+		options.FlushDocuments = DefaultPipelineOptions.FlushDocuments
+	}
+	if options.FlushBytes <= 0 {
+		options.FlushBytes = DefaultPipelineOptions.FlushBytes
+	}
+	if options.QueueSize <= 0 {
+		options.QueueSize = DefaultPipelineOptions.QueueSize
+	}
+	if options.ProgressInterval <= 0 {
+		options.ProgressInterval = DefaultPipelineOptions.ProgressInterval
+	}
+	if options.RetryPolicy == (BulkRetryPolicy{}) {
+		options.RetryPolicy = DefaultPipelineOptions.RetryPolicy
+	}
+	return options
+}
+
+// bulkBatcher накапливает операции и отдаёт пакет, когда следующая операция превысила бы лимиты
+type bulkBatcher struct {
+	maxDocuments int
+	maxBytes     int
+	actions      []bulkAction
+	bytes        int
+}
+
+// add добавляет операцию и возвращает готовый к отправке пакет, если текущий заполнен
+func (batcher *bulkBatcher) add(action bulkAction) []bulkAction {
+	var full []bulkAction
+	if len(batcher.actions) > 0 &&
+		(len(batcher.actions) >= batcher.maxDocuments || batcher.bytes+len(action.lines) > batcher.maxBytes) {
+		full = batcher.flush()
+	}
+
+	batcher.actions = append(batcher.actions, action)
+	batcher.bytes += len(action.lines)
+	return full
+}
+
+// flush возвращает накопленные операции и начинает новый пакет
+func (batcher *bulkBatcher) flush() []bulkAction {
+	actions := batcher.actions
+	batcher.actions = nil
+	batcher.bytes = 0
+	return actions
+}
+
+// pipelineProgress — счётчики конвейера для вывода прогресса
+type pipelineProgress struct {
+	read    atomic.Int64
+	indexed atomic.Int64
+	failed  atomic.Int64
+	started time.Time
+}
+
+func (progress *pipelineProgress) log(prefix string) {
+	elapsed := time.Since(progress.started)
+	indexed := progress.indexed.Load()
+	rate := float64(indexed) / elapsed.Seconds()
+
+	log.Printf("%s: прочитано %d, проиндексировано %d, ошибок %d за %s (%.0f док/с)",
+		prefix, progress.read.Load(), indexed, progress.failed.Load(), elapsed.Round(time.Second), rate)
+}
+
+// runIndexingPipeline выгружает все объявления из loader и индексирует их в index.
+// Мягко удалённые объявления loader не возвращает, поэтому новое поколение индекса их не содержит.
+// При ошибке чтения или отправки запроса целиком конвейер останавливается и возвращает первую ошибку;
+// отказы по отдельным документам попадают в отчёт.
+func runIndexingPipeline(esClient *elasticsearch.Client, loader ports.AdvertisementBatchLoader, index string, options PipelineOptions) (IndexingReport, error) {
+	options = options.withDefaults()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var (
+		report    IndexingReport
+		reportMu  sync.Mutex
+		firstErr  error
+		errOnce   sync.Once
+		progress  = &pipelineProgress{started: time.Now()}
+		batches   = make(chan []bulkAction, options.QueueSize)
+		waitGroup sync.WaitGroup
+	)
+
+	fail := func(err error) {
+		errOnce.Do(func() {
+			firstErr = err
+			cancel()
+		})
+	}
+
+	// Воркеры отправляют собранные пакеты
+	for i := 0; i < options.Workers; i++ {
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+			for batch := range batches {
+				if ctx.Err() != nil {
+					continue
+				}
+
+				batchReport, err := executeBulk(esClient, batch, options.RetryPolicy)
+				progress.indexed.Add(int64(batchReport.Indexed))
+				progress.failed.Add(int64(batchReport.Failed))
+
+				reportMu.Lock()
+				report.add(batchReport)
+				reportMu.Unlock()
+
+				if err != nil {
+					fail(fmt.Errorf("ошибка вставки: %w", err))
+				}
+			}
+		}()
+	}
+
+	// Периодический вывод прогресса
+	progressDone := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(options.ProgressInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				progress.log("Прогресс индексации")
+			case <-progressDone:
+				return
+			}
+		}
+	}()
+
+	// Читатель: загружает объявления и собирает пакеты, блокируясь на заполненной очереди
+	if err := readAdvertisements(ctx, loader, index, options, progress, batches); err != nil {
+		fail(err)
+	}
+	close(batches)
+	waitGroup.Wait()
+	close(progressDone)
+
+	progress.log("Индексация завершена")
+	return report, firstErr
+}
+
+// readAdvertisements читает объявления по ключу (id > последнего прочитанного) и отправляет пакеты bulk-операций в batches
+func readAdvertisements(ctx context.Context, loader ports.AdvertisementBatchLoader, index string, options PipelineOptions,
+	progress *pipelineProgress, batches chan<- []bulkAction) error {
+	batcher := &bulkBatcher{maxDocuments: options.FlushDocuments, maxBytes: options.FlushBytes}
+
+	// send возвращает false, если конвейер остановлен из-за ошибки воркера
+	send := func(batch []bulkAction) bool {
+		if len(batch) == 0 {
+			return true
+		}
+		select {
+		case batches <- batch:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	lastID := 0
+	for {
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		advertisements, err := loader.GetAdvertisementsAfter(lastID, options.FetchSize)
+		if err != nil {
+			return fmt.Errorf("ошибка получения объявлений после id %d: %w", lastID, err)
+		}
+		progress.read.Add(int64(len(advertisements)))
+
+		for _, advertisement := range advertisements {
+			action, err := newIndexAction(index, fmt.Sprint(advertisement.Index), advertisementDocument(&advertisement))
+			if err != nil {
+				return err
+			}
+			if !send(batcher.add(action)) {
+				return nil
+			}
+		}
+
+		if len(advertisements) < options.FetchSize {
+			break
+		}
+		lastID = advertisements[len(advertisements)-1].Index
+	}
+
+	send(batcher.flush())
+	return nil
+}
@@ -0,0 +1,172 @@
+package util
+
+import (
+	"SearchService/internal/model"
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/elastic/go-elasticsearch/v8"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestBulkBatcher(t *testing.T) {
+	action := func(size int) bulkAction {
+		return bulkAction{lines: make([]byte, size)}
+	}
+
+	t.Run("flush by document count", func(t *testing.T) {
+		batcher := &bulkBatcher{maxDocuments: 2, maxBytes: 1000}
+		if full := batcher.add(action(10)); full != nil {
+			t.Fatalf("add() flushed %d actions too early", len(full))
+		}
+		batcher.add(action(10))
+		if full := batcher.add(action(10)); len(full) != 2 {
+			t.Fatalf("add() flushed %d actions, want 2", len(full))
+		}
+		if rest := batcher.flush(); len(rest) != 1 {
+			t.Fatalf("flush() returned %d actions, want 1", len(rest))
+		}
+	})
+
+	t.Run("flush by size", func(t *testing.T) {
+		batcher := &bulkBatcher{maxDocuments: 100, maxBytes: 25}
+		batcher.add(action(10))
+		batcher.add(action(10))
+		if full := batcher.add(action(10)); len(full) != 2 {
+			t.Fatalf("add() flushed %d actions, want 2", len(full))
+		}
+	})
+
+	t.Run("oversized action is sent alone", func(t *testing.T) {
+		batcher := &bulkBatcher{maxDocuments: 100, maxBytes: 25}
+		if full := batcher.add(action(50)); full != nil {
+			t.Fatalf("add() flushed empty batch")
+		}
+		if full := batcher.add(action(10)); len(full) != 1 {
+			t.Fatalf("add() flushed %d actions, want 1", len(full))
+		}
+	})
+}
+
+// sliceLoader — AdvertisementBatchLoader поверх отсортированного по id среза в памяти
+type sliceLoader struct {
+	advertisements []model.Advertisement
+}
+
+func (loader *sliceLoader) GetAdvertisementsBatch(limit int, offset int) ([]model.Advertisement, error) {
+	if offset >= len(loader.advertisements) {
+		return nil, nil
+	}
+	end := min(offset+limit, len(loader.advertisements))
+	return loader.advertisements[offset:end], nil
+}
+
+func (loader *sliceLoader) GetAdvertisementsAfter(lastID int, limit int) ([]model.Advertisement, error) {
+	var page []model.Advertisement
+	for _, advertisement := range loader.advertisements {
+		if advertisement.Index > lastID && len(page) < limit {
+			page = append(page, advertisement)
+		}
+	}
+	return page, nil
+}
+
+func (loader *sliceLoader) GetAdvertisementById(id int) (model.Advertisement, error) {
+	for _, advertisement := range loader.advertisements {
+		if advertisement.Index == id {
+			return advertisement, nil
+		}
+	}
+	return model.Advertisement{}, fmt.Errorf("объявление %d не найдено", id)
+}
+
+// bulkTransport отвечает на каждый bulk-запрос успехом для всех операций, кроме индексации документов
+// из rejected, и запоминает id проиндексированных и удалённых документов
+type bulkTransport struct {
+	mu       sync.Mutex
+	ids      map[string]int
+	deleted  map[string]int
+	rejected map[string]bool
+}
+
+func (transport *bulkTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	var items []string
+	hasErrors := false
+	scanner := bufio.NewScanner(request.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 10<<20)
+	for scanner.Scan() {
+		var meta map[string]struct {
+			ID string `json:"_id"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &meta); err != nil {
+			return nil, err
+		}
+
+		transport.mu.Lock()
+		for op, target := range meta {
+			switch op {
+			case "index":
+				// За метаданными index следует строка документа
+				scanner.Scan()
+				if transport.rejected[target.ID] {
+					hasErrors = true
+					items = append(items, fmt.Sprintf(`{"index":{"_id":%q,"status":400,"error":{"type":"mapper_parsing_exception","reason":"failed to parse"}}}`, target.ID))
+					continue
+				}
+				transport.ids[target.ID]++
+				items = append(items, fmt.Sprintf(`{"index":{"_id":%q,"status":201}}`, target.ID))
+			case "delete":
+				if transport.deleted == nil {
+					transport.deleted = make(map[string]int)
+				}
+				transport.deleted[target.ID]++
+				items = append(items, fmt.Sprintf(`{"delete":{"_id":%q,"status":200,"result":"deleted"}}`, target.ID))
+			}
+		}
+		transport.mu.Unlock()
+	}
+
+	body := fmt.Sprintf(`{"errors":%t,"items":[%s]}`, hasErrors, strings.Join(items, ","))
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"X-Elastic-Product": []string{"Elasticsearch"}, "Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(bytes.NewReader([]byte(body))),
+	}, nil
+}
+
+func TestRunIndexingPipeline(t *testing.T) {
+	loader := &sliceLoader{}
+	for i := 1; i <= 2500; i++ {
+		// id с пропусками, как после удалений в таблице
+		loader.advertisements = append(loader.advertisements, model.Advertisement{Index: i * 3, Name: fmt.Sprint("Product ", i)})
+	}
+
+	transport := &bulkTransport{ids: make(map[string]int)}
+	esClient, err := elasticsearch.NewClient(elasticsearch.Config{Transport: transport})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	options := PipelineOptions{Workers: 3, FetchSize: 1000, FlushDocuments: 300, QueueSize: 2}
+	report, err := runIndexingPipeline(esClient, loader, "advertisements_v1", options)
+	if err != nil {
+		t.Fatalf("runIndexingPipeline() error = %v", err)
+	}
+
+	if report.Indexed != 2500 || report.Failed != 0 {
+		t.Errorf("runIndexingPipeline() report = %+v, want 2500 indexed", report)
+	}
+	if len(transport.ids) != 2500 {
+		t.Errorf("transport received %d distinct documents, want 2500", len(transport.ids))
+	}
+	for id, count := range transport.ids {
+		if count != 1 {
+			t.Errorf("document %s sent %d times", id, count)
+		}
+	}
+}
@@ -0,0 +1,341 @@
+package util
+
+// reindex содержит переиндексацию без простоя: данные загружаются в новое поколение индекса
+// advertisements_v{N}, после проверки количества документов алиас advertisements атомарно
+// переключается на него, а старые поколения удаляются согласно настройке хранения.
+// Записи, внесённые во время выгрузки, попадают в старое поколение через алиас; чтобы они не
+// потерялись, изменения с начала переиндексации по updated_at переносятся в новое поколение
+// догоняющим проходом до переключения алиаса и ещё одним — после него.
+
+import (
+	"SearchService/internal/model"
+	"SearchService/internal/ports"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/elastic/go-elasticsearch/v8"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ReindexOptions — настройки переиндексации
+type ReindexOptions struct {
+	// Retention — сколько предыдущих поколений индекса оставить после переключения алиаса
+	Retention int
+	// Pipeline — настройки конвейера загрузки нового поколения
+	Pipeline PipelineOptions
+	// RequiredFields — поля, которые должны быть заполнены во всех документах нового поколения
+	RequiredFields []string
+	// CatchUp — настройки догоняющих проходов. Lag — запас назад от начала прохода на транзакции,
+	// начатые раньше него и зафиксированные позже.
+	CatchUp SyncOptions
+}
+
+// ReindexResult — итог переиндексации
+type ReindexResult struct {
+	Index  string
+	Report IndexingReport
+	// Previous — индексы, на которые алиас указывал до переключения
+	Previous []string
+	// Deleted — поколения, удалённые по настройке хранения
+	Deleted []string
+}
+
+// GenerationIndexName возвращает имя индекса для поколения generation
+func GenerationIndexName(generation int) string {
+	return fmt.Sprintf("%s_v%d", AdvertisementsIndex, generation)
+}
+
+// ReindexAdvertisements строит новое поколение индекса и переключает на него алиас advertisements.
+// Если загрузка, проверка количества документов, догоняющий проход или переключение алиаса не удались,
+// новое поколение удаляется, а алиас продолжает указывать на прежний индекс.
+func ReindexAdvertisements(esClient *elasticsearch.Client, loader ports.AdvertisementReindexLoader, options ReindexOptions) (ReindexResult, error) {
+	options.CatchUp = options.CatchUp.withDefaults()
+
+	generations, err := listGenerations(esClient)
+	if err != nil {
+		return ReindexResult{}, err
+	}
+
+	nextGeneration := 1
+	if len(generations) > 0 {
+		nextGeneration = generations[len(generations)-1] + 1
+	}
+	index := GenerationIndexName(nextGeneration)
+
+	startedAt, err := loader.GetDatabaseTime()
+	if err != nil {
+		return ReindexResult{}, err
+	}
+
+	if err := CreateAdvertisementsIndex(esClient, index); err != nil {
+		return ReindexResult{}, err
+	}
+	log.Printf("Создан индекс %s", index)
+
+	report, err := runIndexingPipeline(esClient, loader, index, options.Pipeline)
+	if err == nil && report.Failed > 0 {
+		err = fmt.Errorf("не удалось проиндексировать %d документов", report.Failed)
+	}
+	if err == nil {
+		err = validateDocumentCount(esClient, index, report.Indexed)
+	}
+	if err == nil {
+		err = validateRequiredFields(esClient, index, options.RequiredFields)
+	}
+
+	// Второй проход после переключения начинается с начала первого: всё, что до переключения
+	// записано в старое поколение, зафиксировано в БД раньше, чем второй проход её прочитает
+	var swapCatchUpFrom time.Time
+	if err == nil {
+		swapCatchUpFrom, err = loader.GetDatabaseTime()
+	}
+	if err == nil {
+		err = catchUpSince(esClient, loader, index, startedAt, options.CatchUp, &report)
+	}
+
+	var previous []string
+	if err == nil {
+		previous, err = swapAlias(esClient, index)
+	}
+	if err != nil {
+		if deleteErr := deleteIndices(esClient, []string{index}); deleteErr != nil {
+			log.Printf("не удалось удалить недостроенный индекс %s: %v", index, deleteErr)
+		}
+		return ReindexResult{Index: index, Report: report}, fmt.Errorf("переиндексация в %s прервана: %w", index, err)
+	}
+	log.Printf("Алиас %s переключён на %s", AdvertisementsIndex, index)
+
+	if err := catchUpSince(esClient, loader, index, swapCatchUpFrom, options.CatchUp, &report); err != nil {
+		return ReindexResult{Index: index, Report: report, Previous: previous},
+			fmt.Errorf("алиас переключён на %s, но изменения во время переключения не перенесены, запустите синхронизацию: %w", index, err)
+	}
+
+	result := ReindexResult{Index: index, Report: report, Previous: previous}
+
+	// Поколения старше текущего, кроме последних options.Retention, удаляются
+	if len(generations) > options.Retention {
+		for _, generation := range generations[:len(generations)-options.Retention] {
+			result.Deleted = append(result.Deleted, GenerationIndexName(generation))
+		}
+		if err := deleteIndices(esClient, result.Deleted); err != nil {
+			return result, fmt.Errorf("ошибка удаления старых поколений: %w", err)
+		}
+	}
+
+	return result, nil
+}
+
+// catchUpSince переносит в index изменения, внесённые после since - options.Lag, и добавляет их в report
+func catchUpSince(esClient *elasticsearch.Client, loader ports.AdvertisementChangeLoader, index string,
+	since time.Time, options SyncOptions, report *IndexingReport) error {
+	watermark, catchUpReport, err := catchUpChanges(esClient, loader, index, model.SyncWatermark{UpdatedAt: since.Add(-options.Lag)}, options)
+	report.add(catchUpReport)
+	if err != nil {
+		return fmt.Errorf("ошибка догоняющего прохода: %w", err)
+	}
+
+	if catchUpReport.Indexed > 0 || catchUpReport.Deleted > 0 {
+		log.Printf("Перенесены изменения во время переиндексации в %s: проиндексировано %d, удалено %d, отметка %s",
+			index, catchUpReport.Indexed, catchUpReport.Deleted, watermark.UpdatedAt.Format(time.RFC3339Nano))
+	}
+
+	return nil
+}
+
+// listGenerations возвращает номера существующих поколений индекса по возрастанию
+func listGenerations(esClient *elasticsearch.Client) ([]int, error) {
+	response, err := esClient.Cat.Indices(
+		esClient.Cat.Indices.WithIndex(AdvertisementsIndex+"_v*"),
+		esClient.Cat.Indices.WithFormat("json"),
+		esClient.Cat.Indices.WithH("index"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения списка индексов: %w", err)
+	}
+	defer response.Body.Close()
+
+	if response.IsError() {
+		return nil, fmt.Errorf("ошибка получения списка индексов: %s", response.String())
+	}
+
+	var indices []struct {
+		Index string `json:"index"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&indices); err != nil {
+		return nil, fmt.Errorf("ошибка парсинга списка индексов: %w", err)
+	}
+
+	var generations []int
+	for _, index := range indices {
+		if generation, ok := parseGeneration(index.Index); ok {
+			generations = append(generations, generation)
+		}
+	}
+	sort.Ints(generations)
+
+	return generations, nil
+}
+
+// parseGeneration извлекает номер поколения из имени индекса advertisements_v{N}
+func parseGeneration(index string) (int, bool) {
+	suffix, found := strings.CutPrefix(index, AdvertisementsIndex+"_v")
+	if !found {
+		return 0, false
+	}
+
+	generation, err := strconv.Atoi(suffix)
+	if err != nil || generation <= 0 {
+		return 0, false
+	}
+
+	return generation, true
+}
+
+// validateDocumentCount сверяет количество документов в индексе с количеством проиндексированных строк
+func validateDocumentCount(esClient *elasticsearch.Client, index string, expected int) error {
+	refresh, err := esClient.Indices.Refresh(esClient.Indices.Refresh.WithIndex(index))
+	if err != nil {
+		return fmt.Errorf("ошибка обновления индекса %s: %w", index, err)
+	}
+	refresh.Body.Close()
+
+	response, err := esClient.Count(esClient.Count.WithIndex(index))
+	if err != nil {
+		return fmt.Errorf("ошибка подсчёта документов в %s: %w", index, err)
+	}
+	defer response.Body.Close()
+
+	if response.IsError() {
+		return fmt.Errorf("ошибка подсчёта документов в %s: %s", index, response.String())
+	}
+
+	var count struct {
+		Count int `json:"count"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&count); err != nil {
+		return fmt.Errorf("ошибка парсинга количества документов: %w", err)
+	}
+
+	if count.Count != expected {
+		return fmt.Errorf("в индексе %s %d документов, загружено из БД %d", index, count.Count, expected)
+	}
+
+	return nil
+}
+
+// validateRequiredFields проверяет, что ни в одном документе index не пусты поля из fields
+func validateRequiredFields(esClient *elasticsearch.Client, index string, fields []string) error {
+	if len(fields) == 0 {
+		return nil
+	}
+
+	blank, err := CheckRequiredFields(esClient, index, fields)
+	if err != nil {
+		return err
+	}
+	if len(blank) > 0 {
+		return fmt.Errorf("в индексе %s есть документы с пустыми полями: %s", index, formatBlankFields(blank))
+	}
+
+	return nil
+}
+
+// swapAlias атомарно переключает алиас advertisements на index и возвращает прежние индексы алиаса.
+// Если advertisements — обычный индекс, созданный до перехода на поколения, он удаляется в той же операции.
+func swapAlias(esClient *elasticsearch.Client, index string) ([]string, error) {
+	previous, legacyIndex, err := aliasTargets(esClient)
+	if err != nil {
+		return nil, err
+	}
+
+	var actions []map[string]interface{}
+	if legacyIndex {
+		actions = append(actions, map[string]interface{}{
+			"remove_index": map[string]string{"index": AdvertisementsIndex},
+		})
+	}
+	for _, previousIndex := range previous {
+		actions = append(actions, map[string]interface{}{
+			"remove": map[string]string{"index": previousIndex, "alias": AdvertisementsIndex},
+		})
+	}
+	actions = append(actions, map[string]interface{}{
+		"add": map[string]interface{}{"index": index, "alias": AdvertisementsIndex, "is_write_index": true},
+	})
+
+	body, err := json.Marshal(map[string]interface{}{"actions": actions})
+	if err != nil {
+		return nil, fmt.Errorf("ошибка сериализации действий с алиасом: %w", err)
+	}
+
+	response, err := esClient.Indices.UpdateAliases(bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка переключения алиаса: %w", err)
+	}
+	defer response.Body.Close()
+
+	if response.IsError() {
+		return nil, fmt.Errorf("ошибка переключения алиаса: %s", response.String())
+	}
+
+	return previous, nil
+}
+
+// aliasTargets возвращает индексы, на которые указывает алиас advertisements.
+// legacyIndex = true, если вместо алиаса существует обычный индекс с этим именем.
+func aliasTargets(esClient *elasticsearch.Client) (targets []string, legacyIndex bool, err error) {
+	response, err := esClient.Indices.GetAlias(esClient.Indices.GetAlias.WithName(AdvertisementsIndex))
+	if err != nil {
+		return nil, false, fmt.Errorf("ошибка получения алиаса: %w", err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode == http.StatusNotFound {
+		exists, err := esClient.Indices.Exists([]string{AdvertisementsIndex})
+		if err != nil {
+			return nil, false, fmt.Errorf("ошибка проверки индекса: %w", err)
+		}
+		exists.Body.Close()
+
+		return nil, exists.StatusCode == http.StatusOK, nil
+	}
+
+	if response.IsError() {
+		return nil, false, fmt.Errorf("ошибка получения алиаса: %s", response.String())
+	}
+
+	var aliases map[string]json.RawMessage
+	if err := json.NewDecoder(response.Body).Decode(&aliases); err != nil {
+		return nil, false, fmt.Errorf("ошибка парсинга алиаса: %w", err)
+	}
+
+	for index := range aliases {
+		targets = append(targets, index)
+	}
+	sort.Strings(targets)
+
+	return targets, false, nil
+}
+
+func deleteIndices(esClient *elasticsearch.Client, indices []string) error {
+	if len(indices) == 0 {
+		return nil
+	}
+
+	response, err := esClient.Indices.Delete(indices)
+	if err != nil {
+		return fmt.Errorf("ошибка удаления индексов %v: %w", indices, err)
+	}
+	defer response.Body.Close()
+
+	if response.IsError() {
+		return fmt.Errorf("ошибка удаления индексов %v: %s", indices, response.String())
+	}
+
+	return nil
+}
@@ -0,0 +1,366 @@
+package util
+
+import (
+	"SearchService/internal/model"
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/elastic/go-elasticsearch/v8"
+	"io"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParseGeneration(t *testing.T) {
+	tests := []struct {
+		input  string
+		want   int
+		wantOk bool
+	}{
+		{input: "advertisements_v1", want: 1, wantOk: true},
+		{input: "advertisements_v12", want: 12, wantOk: true},
+		{input: "advertisements", wantOk: false},
+		{input: "advertisements_v0", wantOk: false},
+		{input: "advertisements_vnext", wantOk: false},
+		{input: "other_v3", wantOk: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, ok := parseGeneration(tt.input)
+			if ok != tt.wantOk || got != tt.want {
+				t.Errorf("parseGeneration(%q) = %d, %v, want %d, %v", tt.input, got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
+
+// clusterTransport имитирует кластер Elasticsearch в объёме, нужном переиндексации:
+// список поколений, создание и удаление индексов, bulk, подсчёт документов и алиас advertisements
+type clusterTransport struct {
+	mu sync.Mutex
+	// indices — существующие индексы и id документов в них
+	indices map[string]map[string]bool
+	// aliasTargets — индексы, на которые указывает алиас advertisements
+	aliasTargets []string
+	// legacyIndex — вместо алиаса существует обычный индекс advertisements
+	legacyIndex bool
+	// failAliases — запрос на переключение алиаса завершается ошибкой
+	failAliases bool
+
+	aliasActions []map[string]map[string]interface{}
+	deleted      []string
+}
+
+func newClusterTransport(indices ...string) *clusterTransport {
+	transport := &clusterTransport{indices: make(map[string]map[string]bool)}
+	for _, index := range indices {
+		transport.indices[index] = make(map[string]bool)
+	}
+	return transport
+}
+
+func (transport *clusterTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	transport.mu.Lock()
+	defer transport.mu.Unlock()
+
+	path := request.URL.Path
+	switch {
+	case strings.HasPrefix(path, "/_cat/indices"):
+		var indices []map[string]string
+		for index := range transport.indices {
+			if strings.HasPrefix(index, AdvertisementsIndex+"_v") {
+				indices = append(indices, map[string]string{"index": index})
+			}
+		}
+		return clusterResponse(http.StatusOK, indices)
+	case path == "/_bulk":
+		return transport.bulk(request.Body)
+	case path == "/_alias/"+AdvertisementsIndex:
+		if len(transport.aliasTargets) == 0 {
+			return clusterResponse(http.StatusNotFound, map[string]interface{}{"error": "alias [advertisements] missing", "status": 404})
+		}
+		aliases := make(map[string]interface{})
+		for _, index := range transport.aliasTargets {
+			aliases[index] = map[string]interface{}{"aliases": map[string]interface{}{AdvertisementsIndex: map[string]interface{}{}}}
+		}
+		return clusterResponse(http.StatusOK, aliases)
+	case path == "/_aliases":
+		return transport.updateAliases(request.Body)
+	case strings.HasSuffix(path, "/_refresh"):
+		return clusterResponse(http.StatusOK, map[string]interface{}{})
+	case strings.HasSuffix(path, "/_count"):
+		index := strings.TrimSuffix(strings.TrimPrefix(path, "/"), "/_count")
+		return clusterResponse(http.StatusOK, map[string]int{"count": len(transport.indices[index])})
+	}
+
+	name := strings.TrimPrefix(path, "/")
+	switch request.Method {
+	case http.MethodHead:
+		if name == AdvertisementsIndex && transport.legacyIndex {
+			return clusterResponse(http.StatusOK, nil)
+		}
+		return clusterResponse(http.StatusNotFound, nil)
+	case http.MethodPut:
+		transport.indices[name] = make(map[string]bool)
+		return clusterResponse(http.StatusOK, map[string]interface{}{"acknowledged": true, "index": name})
+	case http.MethodDelete:
+		for _, index := range strings.Split(name, ",") {
+			delete(transport.indices, index)
+			transport.deleted = append(transport.deleted, index)
+		}
+		return clusterResponse(http.StatusOK, map[string]bool{"acknowledged": true})
+	}
+
+	return clusterResponse(http.StatusBadRequest, map[string]string{"error": "unexpected request " + request.Method + " " + path})
+}
+
+func (transport *clusterTransport) bulk(body io.Reader) (*http.Response, error) {
+	var items []map[string]interface{}
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 10<<20)
+	for scanner.Scan() {
+		var meta map[string]struct {
+			Index string `json:"_index"`
+			ID    string `json:"_id"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &meta); err != nil {
+			return nil, err
+		}
+
+		for op, target := range meta {
+			documents, found := transport.indices[target.Index]
+			if !found {
+				return nil, fmt.Errorf("bulk в несуществующий индекс %s", target.Index)
+			}
+			switch op {
+			case "index":
+				scanner.Scan()
+				documents[target.ID] = true
+				items = append(items, map[string]interface{}{"index": map[string]interface{}{"_id": target.ID, "status": 201}})
+			case "delete":
+				delete(documents, target.ID)
+				items = append(items, map[string]interface{}{"delete": map[string]interface{}{"_id": target.ID, "status": 200}})
+			}
+		}
+	}
+
+	return clusterResponse(http.StatusOK, map[string]interface{}{"errors": false, "items": items})
+}
+
+func (transport *clusterTransport) updateAliases(body io.Reader) (*http.Response, error) {
+	if transport.failAliases {
+		return clusterResponse(http.StatusInternalServerError, map[string]string{"error": "cluster unavailable"})
+	}
+
+	var request struct {
+		Actions []map[string]map[string]interface{} `json:"actions"`
+	}
+	if err := json.NewDecoder(body).Decode(&request); err != nil {
+		return nil, err
+	}
+	transport.aliasActions = request.Actions
+
+	for _, action := range request.Actions {
+		for op, params := range action {
+			index, _ := params["index"].(string)
+			switch op {
+			case "remove_index":
+				delete(transport.indices, index)
+				transport.legacyIndex = false
+			case "remove":
+				var targets []string
+				for _, target := range transport.aliasTargets {
+					if target != index {
+						targets = append(targets, target)
+					}
+				}
+				transport.aliasTargets = targets
+			case "add":
+				transport.aliasTargets = append(transport.aliasTargets, index)
+			}
+		}
+	}
+
+	return clusterResponse(http.StatusOK, map[string]bool{"acknowledged": true})
+}
+
+func (transport *clusterTransport) documents(index string) []string {
+	transport.mu.Lock()
+	defer transport.mu.Unlock()
+
+	var ids []string
+	for id := range transport.indices[index] {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func clusterResponse(status int, body interface{}) (*http.Response, error) {
+	var encoded []byte
+	if body != nil {
+		var err error
+		if encoded, err = json.Marshal(body); err != nil {
+			return nil, err
+		}
+	}
+
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{"X-Elastic-Product": []string{"Elasticsearch"}, "Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(bytes.NewReader(encoded)),
+	}, nil
+}
+
+// reindexLoader — AdvertisementReindexLoader в памяти. Часы БД остановлены на startedAt,
+// чтобы изменения из changes попадали в окно догоняющих проходов.
+type reindexLoader struct {
+	*sliceLoader
+	*changeLoader
+	startedAt time.Time
+}
+
+func (loader *reindexLoader) GetDatabaseTime() (time.Time, error) {
+	return loader.startedAt, nil
+}
+
+func newReindexLoader(ids ...int) *reindexLoader {
+	loader := &reindexLoader{
+		sliceLoader:  &sliceLoader{},
+		changeLoader: &changeLoader{},
+		startedAt:    time.Now().Add(-time.Minute),
+	}
+	for _, id := range ids {
+		loader.advertisements = append(loader.advertisements, model.Advertisement{Index: id, Name: fmt.Sprint("Product ", id)})
+	}
+	return loader
+}
+
+func reindexClient(t *testing.T, transport http.RoundTripper) *elasticsearch.Client {
+	t.Helper()
+	esClient, err := elasticsearch.NewClient(elasticsearch.Config{Transport: transport})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return esClient
+}
+
+func TestReindexAdvertisementsSwapsAliasAndDeletesOldGenerations(t *testing.T) {
+	transport := newClusterTransport("advertisements_v1", "advertisements_v2", "other_index")
+	transport.aliasTargets = []string{"advertisements_v2"}
+
+	result, err := ReindexAdvertisements(reindexClient(t, transport), newReindexLoader(1, 2, 3), ReindexOptions{Retention: 1})
+	if err != nil {
+		t.Fatalf("ReindexAdvertisements() error = %v", err)
+	}
+
+	if result.Index != "advertisements_v3" {
+		t.Errorf("Index = %q, want advertisements_v3", result.Index)
+	}
+	if !reflect.DeepEqual(result.Previous, []string{"advertisements_v2"}) {
+		t.Errorf("Previous = %v, want [advertisements_v2]", result.Previous)
+	}
+	if !reflect.DeepEqual(result.Deleted, []string{"advertisements_v1"}) || !reflect.DeepEqual(transport.deleted, []string{"advertisements_v1"}) {
+		t.Errorf("Deleted = %v, cluster deleted %v, want only advertisements_v1", result.Deleted, transport.deleted)
+	}
+	if !reflect.DeepEqual(transport.aliasTargets, []string{"advertisements_v3"}) {
+		t.Errorf("alias points to %v, want [advertisements_v3]", transport.aliasTargets)
+	}
+	if got := transport.documents("advertisements_v3"); !reflect.DeepEqual(got, []string{"1", "2", "3"}) {
+		t.Errorf("advertisements_v3 documents = %v, want [1 2 3]", got)
+	}
+
+	wantActions := []map[string]map[string]interface{}{
+		{"remove": {"index": "advertisements_v2", "alias": AdvertisementsIndex}},
+		{"add": {"index": "advertisements_v3", "alias": AdvertisementsIndex, "is_write_index": true}},
+	}
+	if !reflect.DeepEqual(transport.aliasActions, wantActions) {
+		t.Errorf("alias actions = %v, want %v", transport.aliasActions, wantActions)
+	}
+}
+
+func TestReindexAdvertisementsReplacesLegacyIndex(t *testing.T) {
+	transport := newClusterTransport(AdvertisementsIndex)
+	transport.legacyIndex = true
+
+	result, err := ReindexAdvertisements(reindexClient(t, transport), newReindexLoader(1, 2), ReindexOptions{Retention: 1})
+	if err != nil {
+		t.Fatalf("ReindexAdvertisements() error = %v", err)
+	}
+
+	if result.Index != "advertisements_v1" || len(result.Previous) != 0 || len(result.Deleted) != 0 {
+		t.Errorf("result = %+v, want advertisements_v1 without previous or deleted generations", result)
+	}
+
+	// Обычный индекс удаляется той же операцией, что добавляет алиас, иначе имя было бы занято
+	wantActions := []map[string]map[string]interface{}{
+		{"remove_index": {"index": AdvertisementsIndex}},
+		{"add": {"index": "advertisements_v1", "alias": AdvertisementsIndex, "is_write_index": true}},
+	}
+	if !reflect.DeepEqual(transport.aliasActions, wantActions) {
+		t.Errorf("alias actions = %v, want %v", transport.aliasActions, wantActions)
+	}
+	if _, found := transport.indices[AdvertisementsIndex]; found {
+		t.Errorf("legacy index %s was not removed", AdvertisementsIndex)
+	}
+}
+
+func TestReindexAdvertisementsRemovesNewIndexWhenSwapFails(t *testing.T) {
+	transport := newClusterTransport("advertisements_v1", "advertisements_v2")
+	transport.aliasTargets = []string{"advertisements_v2"}
+	transport.failAliases = true
+
+	result, err := ReindexAdvertisements(reindexClient(t, transport), newReindexLoader(1, 2, 3), ReindexOptions{Retention: 0})
+	if err == nil {
+		t.Fatal("ReindexAdvertisements() error = nil, want alias swap failure")
+	}
+
+	if result.Index != "advertisements_v3" {
+		t.Errorf("Index = %q, want advertisements_v3", result.Index)
+	}
+	// Удаляется только недостроенное поколение: без переключения старые поколения остаются в работе
+	if !reflect.DeepEqual(transport.deleted, []string{"advertisements_v3"}) {
+		t.Errorf("cluster deleted %v, want only advertisements_v3", transport.deleted)
+	}
+	if !reflect.DeepEqual(transport.aliasTargets, []string{"advertisements_v2"}) {
+		t.Errorf("alias points to %v, want [advertisements_v2]", transport.aliasTargets)
+	}
+}
+
+func TestReindexAdvertisementsCatchesUpChanges(t *testing.T) {
+	transport := newClusterTransport("advertisements_v1")
+	transport.aliasTargets = []string{"advertisements_v1"}
+
+	loader := newReindexLoader(1, 2, 3)
+	// Изменения, зафиксированные во время выгрузки: обновление, новая строка и мягкое удаление
+	changedAt := loader.startedAt.Add(time.Second)
+	loader.changes = []model.AdvertisementChange{
+		{Advertisement: model.Advertisement{Index: 2, Name: "Product 2 updated"}, UpdatedAt: changedAt},
+		{Advertisement: model.Advertisement{Index: 4, Name: "Product 4"}, UpdatedAt: changedAt},
+		{Advertisement: model.Advertisement{Index: 1}, UpdatedAt: changedAt, DeletedAt: &changedAt},
+	}
+	// Изменение до начала выгрузки уже попало в неё и догоняющими проходами не переносится
+	loader.changes = append(loader.changes, model.AdvertisementChange{
+		Advertisement: model.Advertisement{Index: 3, Name: "Product 3"}, UpdatedAt: loader.startedAt.Add(-time.Hour),
+	})
+
+	result, err := ReindexAdvertisements(reindexClient(t, transport), loader, ReindexOptions{Retention: 1})
+	if err != nil {
+		t.Fatalf("ReindexAdvertisements() error = %v", err)
+	}
+
+	if got := transport.documents("advertisements_v2"); !reflect.DeepEqual(got, []string{"2", "3", "4"}) {
+		t.Errorf("advertisements_v2 documents = %v, want [2 3 4]", got)
+	}
+	// Оба прохода — до и после переключения алиаса — переносят изменения с начала выгрузки
+	if result.Report.Indexed != 3+2*2 || result.Report.Deleted != 2 {
+		t.Errorf("Report = %+v, want 7 indexed and 2 deleted", result.Report)
+	}
+}
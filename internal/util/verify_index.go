@@ -0,0 +1,434 @@
+package util
+
+// verify_index содержит сверку индекса advertisements с таблицей advertisements: обе стороны читаются
+// потоком в порядке id и сравниваются слиянием, для каждой пары документов сравниваются контрольные суммы
+// индексируемых полей. Найденные расхождения при необходимости исправляются через Bulk API.
+
+import (
+	"SearchService/internal/model"
+	"SearchService/internal/ports"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/elastic/go-elasticsearch/v8"
+	"strconv"
+)
+
+// DiffKind — вид расхождения индекса и БД
+type DiffKind string
+
+const (
+	// DiffMissing — строка есть в БД, документа в индексе нет
+	DiffMissing DiffKind = "missing"
+	// DiffExtra — документ есть в индексе, строки в БД нет
+	DiffExtra DiffKind = "extra"
+	// DiffStale — документ отличается от строки в БД
+	DiffStale DiffKind = "stale"
+)
+
+// VerifyOptions — настройки сверки
+type VerifyOptions struct {
+	// FetchSize — количество строк и документов, читаемых за один запрос с каждой стороны
+	FetchSize int
+	// Samples — сколько id каждого вида расхождений сохранить в отчёте
+	Samples int
+	// Repair — исправлять расхождения: индексировать отсутствующие и устаревшие документы, удалять лишние
+	Repair bool
+	// RetryPolicy — повтор временно отклонённых операций при исправлении
+	RetryPolicy BulkRetryPolicy
+}
+
+// DefaultVerifyOptions используются для полей VerifyOptions, оставленных нулевыми
+var DefaultVerifyOptions = VerifyOptions{
+	FetchSize:   1000,
+	Samples:     20,
+	RetryPolicy: DefaultBulkRetryPolicy,
+}
+
+func (options VerifyOptions) withDefaults() VerifyOptions {
+	if options.FetchSize <= 0 {
+		options.FetchSize = DefaultVerifyOptions.FetchSize
+	}
+	if options.Samples <= 0 {
+		options.Samples = DefaultVerifyOptions.Samples
+	}
+	if options.RetryPolicy == (BulkRetryPolicy{}) {
+		options.RetryPolicy = DefaultVerifyOptions.RetryPolicy
+	}
+	return options
+}
+
+// VerifyReport — итог сверки
+type VerifyReport struct {
+	// Rows и Documents — сколько строк БД и документов индекса просмотрено
+	Rows      int `json:"rows"`
+	Documents int `json:"documents"`
+	// Counts — количество расхождений каждого вида, Samples — примеры id
+	Counts  map[DiffKind]int      `json:"counts"`
+	Samples map[DiffKind][]string `json:"samples"`
+	// Repair — итог исправления, если оно выполнялось
+	Repair *IndexingReport `json:"repair,omitempty"`
+}
+
+// Consistent сообщает, что расхождений не найдено
+func (report *VerifyReport) Consistent() bool {
+	for _, count := range report.Counts {
+		if count > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// documentDiff — одно расхождение; advertisement заполнен для missing и stale
+type documentDiff struct {
+	kind          DiffKind
+	id            int
+	advertisement *model.Advertisement
+}
+
+// sourceDocument — документ одной из сторон сверки
+type sourceDocument struct {
+	id            int
+	checksum      string
+	advertisement *model.Advertisement
+}
+
+// documentCursor последовательно отдаёт документы в порядке возрастания id; ok = false в конце потока
+type documentCursor interface {
+	next() (document sourceDocument, ok bool, err error)
+}
+
+// VerifyAdvertisementsIndex сверяет индекс advertisements с таблицей и, если включено options.Repair,
+// исправляет расхождения. Сверка идёт по снимку индекса (point in time), поэтому изменения,
+// сделанные во время сверки, могут попасть в отчёт как расхождения.
+func VerifyAdvertisementsIndex(esClient *elasticsearch.Client, loader ports.AdvertisementBatchLoader, options VerifyOptions) (VerifyReport, error) {
+	options = options.withDefaults()
+
+	report := VerifyReport{Counts: make(map[DiffKind]int), Samples: make(map[DiffKind][]string)}
+
+	indexCursor, err := openIndexCursor(esClient, AdvertisementsIndex, options.FetchSize)
+	if err != nil {
+		return report, err
+	}
+	defer indexCursor.close()
+
+	var repair *bulkRepairer
+	if options.Repair {
+		repair = &bulkRepairer{esClient: esClient, options: options, report: &IndexingReport{}}
+		report.Repair = repair.report
+	}
+
+	databaseCursor := &databaseCursor{loader: loader, fetchSize: options.FetchSize}
+	rows, documents, err := compareDocuments(databaseCursor, indexCursor, func(diff documentDiff) error {
+		report.Counts[diff.kind]++
+		if len(report.Samples[diff.kind]) < options.Samples {
+			report.Samples[diff.kind] = append(report.Samples[diff.kind], strconv.Itoa(diff.id))
+		}
+		if repair != nil {
+			return repair.add(diff)
+		}
+		return nil
+	})
+	report.Rows, report.Documents = rows, documents
+	if err != nil {
+		return report, err
+	}
+
+	if repair != nil {
+		if err := repair.flush(); err != nil {
+			return report, err
+		}
+	}
+
+	return report, nil
+}
+
+// compareDocuments сливает два упорядоченных по id потока и передаёт каждое расхождение в visit.
+// Возвращает количество просмотренных документов каждой стороны.
+func compareDocuments(database documentCursor, index documentCursor, visit func(documentDiff) error) (int, int, error) {
+	rows, documents := 0, 0
+
+	row, hasRow, err := database.next()
+	if err != nil {
+		return rows, documents, err
+	}
+	document, hasDocument, err := index.next()
+	if err != nil {
+		return rows, documents, err
+	}
+
+	for hasRow || hasDocument {
+		var diff *documentDiff
+		advanceRow, advanceDocument := false, false
+
+		switch {
+		case hasRow && (!hasDocument || row.id < document.id):
+			diff = &documentDiff{kind: DiffMissing, id: row.id, advertisement: row.advertisement}
+			advanceRow = true
+		case hasDocument && (!hasRow || document.id < row.id):
+			diff = &documentDiff{kind: DiffExtra, id: document.id}
+			advanceDocument = true
+		default:
+			if row.checksum != document.checksum {
+				diff = &documentDiff{kind: DiffStale, id: row.id, advertisement: row.advertisement}
+			}
+			advanceRow, advanceDocument = true, true
+		}
+
+		if diff != nil {
+			if err := visit(*diff); err != nil {
+				return rows, documents, err
+			}
+		}
+
+		if advanceRow {
+			rows++
+			if row, hasRow, err = database.next(); err != nil {
+				return rows, documents, err
+			}
+		}
+		if advanceDocument {
+			documents++
+			if document, hasDocument, err = index.next(); err != nil {
+				return rows, documents, err
+			}
+		}
+	}
+
+	return rows, documents, nil
+}
+
+// documentChecksum вычисляет контрольную сумму документа индекса. Документ предварительно проходит
+// через JSON, чтобы строка из БД и _source из Elasticsearch приводились к одинаковому представлению
+// (числа — float64, ключи — по алфавиту).
+func documentChecksum(document interface{}) (string, error) {
+	encoded, err := json.Marshal(document)
+	if err != nil {
+		return "", fmt.Errorf("ошибка сериализации документа: %w", err)
+	}
+
+	var normalized map[string]interface{}
+	if err := json.Unmarshal(encoded, &normalized); err != nil {
+		return "", fmt.Errorf("ошибка сериализации документа: %w", err)
+	}
+
+	canonical, err := json.Marshal(normalized)
+	if err != nil {
+		return "", fmt.Errorf("ошибка сериализации документа: %w", err)
+	}
+
+	sum := sha256.Sum256(canonical)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// databaseCursor читает объявления из БД по ключу id
+type databaseCursor struct {
+	loader    ports.AdvertisementBatchLoader
+	fetchSize int
+	lastID    int
+	buffer    []model.Advertisement
+	done      bool
+}
+
+func (cursor *databaseCursor) next() (sourceDocument, bool, error) {
+	if len(cursor.buffer) == 0 {
+		if cursor.done {
+			return sourceDocument{}, false, nil
+		}
+
+		page, err := cursor.loader.GetAdvertisementsAfter(cursor.lastID, cursor.fetchSize)
+		if err != nil {
+			return sourceDocument{}, false, fmt.Errorf("ошибка получения объявлений после id %d: %w", cursor.lastID, err)
+		}
+		cursor.done = len(page) < cursor.fetchSize
+		if len(page) == 0 {
+			return sourceDocument{}, false, nil
+		}
+		cursor.buffer = page
+		cursor.lastID = page[len(page)-1].Index
+	}
+
+	advertisement := cursor.buffer[0]
+	cursor.buffer = cursor.buffer[1:]
+
+	checksum, err := documentChecksum(advertisementDocument(&advertisement))
+	if err != nil {
+		return sourceDocument{}, false, err
+	}
+
+	return sourceDocument{id: advertisement.Index, checksum: checksum, advertisement: &advertisement}, true, nil
+}
+
+// indexCursor читает документы индекса в порядке id через point in time и search_after
+type indexCursor struct {
+	esClient    *elasticsearch.Client
+	pitID       string
+	fetchSize   int
+	searchAfter []interface{}
+	buffer      []indexHit
+	done        bool
+}
+
+type indexHit struct {
+	ID     string                 `json:"_id"`
+	Source map[string]interface{} `json:"_source"`
+	Sort   []interface{}          `json:"sort"`
+}
+
+// pitKeepAlive — сколько Elasticsearch хранит снимок между запросами страниц
+const pitKeepAlive = "5m"
+
+func openIndexCursor(esClient *elasticsearch.Client, index string, fetchSize int) (*indexCursor, error) {
+	response, err := esClient.OpenPointInTime([]string{index}, pitKeepAlive)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка открытия снимка индекса %s: %w", index, err)
+	}
+	defer response.Body.Close()
+
+	if response.IsError() {
+		return nil, fmt.Errorf("ошибка открытия снимка индекса %s: %s", index, response.String())
+	}
+
+	var pit struct {
+		ID string `json:"id"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&pit); err != nil {
+		return nil, fmt.Errorf("ошибка парсинга ответа: %w", err)
+	}
+
+	return &indexCursor{esClient: esClient, pitID: pit.ID, fetchSize: fetchSize}, nil
+}
+
+func (cursor *indexCursor) next() (sourceDocument, bool, error) {
+	if len(cursor.buffer) == 0 {
+		if cursor.done {
+			return sourceDocument{}, false, nil
+		}
+		if err := cursor.fetch(); err != nil {
+			return sourceDocument{}, false, err
+		}
+		if len(cursor.buffer) == 0 {
+			return sourceDocument{}, false, nil
+		}
+	}
+
+	hit := cursor.buffer[0]
+	cursor.buffer = cursor.buffer[1:]
+
+	id, err := strconv.Atoi(hit.ID)
+	if err != nil {
+		return sourceDocument{}, false, fmt.Errorf("неверный id документа в индексе: %s", hit.ID)
+	}
+
+	checksum, err := documentChecksum(hit.Source)
+	if err != nil {
+		return sourceDocument{}, false, err
+	}
+
+	return sourceDocument{id: id, checksum: checksum}, true, nil
+}
+
+func (cursor *indexCursor) fetch() error {
+	query := map[string]interface{}{
+		"size": cursor.fetchSize,
+		"pit":  map[string]interface{}{"id": cursor.pitID, "keep_alive": pitKeepAlive},
+		"sort": []map[string]interface{}{{"id": "asc"}},
+	}
+	if cursor.searchAfter != nil {
+		query["search_after"] = cursor.searchAfter
+	}
+
+	body, err := json.Marshal(query)
+	if err != nil {
+		return fmt.Errorf("ошибка сериализации запроса: %w", err)
+	}
+
+	response, err := cursor.esClient.Search(cursor.esClient.Search.WithBody(bytes.NewReader(body)))
+	if err != nil {
+		return fmt.Errorf("ошибка чтения индекса: %w", err)
+	}
+	defer response.Body.Close()
+
+	if response.IsError() {
+		return fmt.Errorf("ошибка чтения индекса: %s", response.String())
+	}
+
+	var result struct {
+		PitID string `json:"pit_id"`
+		Hits  struct {
+			Hits []indexHit `json:"hits"`
+		} `json:"hits"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&result); err != nil {
+		return fmt.Errorf("ошибка парсинга ответа: %w", err)
+	}
+
+	// Elasticsearch может вернуть обновлённый id снимка
+	if result.PitID != "" {
+		cursor.pitID = result.PitID
+	}
+
+	cursor.buffer = result.Hits.Hits
+	cursor.done = len(result.Hits.Hits) < cursor.fetchSize
+	if len(result.Hits.Hits) > 0 {
+		cursor.searchAfter = result.Hits.Hits[len(result.Hits.Hits)-1].Sort
+	}
+
+	return nil
+}
+
+func (cursor *indexCursor) close() {
+	body, err := json.Marshal(map[string]string{"id": cursor.pitID})
+	if err != nil {
+		return
+	}
+
+	response, err := cursor.esClient.ClosePointInTime(cursor.esClient.ClosePointInTime.WithBody(bytes.NewReader(body)))
+	if err == nil {
+		response.Body.Close()
+	}
+}
+
+// bulkRepairer накапливает исправления и отправляет их пакетами
+type bulkRepairer struct {
+	esClient *elasticsearch.Client
+	options  VerifyOptions
+	actions  []bulkAction
+	report   *IndexingReport
+}
+
+func (repairer *bulkRepairer) add(diff documentDiff) error {
+	var action bulkAction
+	var err error
+	if diff.kind == DiffExtra {
+		action, err = newDeleteAction(AdvertisementsIndex, strconv.Itoa(diff.id))
+	} else {
+		action, err = newIndexAction(AdvertisementsIndex, strconv.Itoa(diff.id), advertisementDocument(diff.advertisement))
+	}
+	if err != nil {
+		return err
+	}
+
+	repairer.actions = append(repairer.actions, action)
+	if len(repairer.actions) >= repairer.options.FetchSize {
+		return repairer.flush()
+	}
+	return nil
+}
+
+func (repairer *bulkRepairer) flush() error {
+	if len(repairer.actions) == 0 {
+		return nil
+	}
+
+	batchReport, err := executeBulk(repairer.esClient, repairer.actions, repairer.options.RetryPolicy)
+	repairer.report.add(batchReport)
+	repairer.actions = nil
+	if err != nil {
+		return fmt.Errorf("ошибка исправления расхождений: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package util
+
+import (
+	"SearchService/internal/model"
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+// sliceCursor — documentCursor поверх среза документов
+type sliceCursor struct {
+	documents []sourceDocument
+}
+
+func (cursor *sliceCursor) next() (sourceDocument, bool, error) {
+	if len(cursor.documents) == 0 {
+		return sourceDocument{}, false, nil
+	}
+	document := cursor.documents[0]
+	cursor.documents = cursor.documents[1:]
+	return document, true, nil
+}
+
+func TestCompareDocuments(t *testing.T) {
+	database := &sliceCursor{documents: []sourceDocument{
+		{id: 1, checksum: "a"}, {id: 2, checksum: "b"}, {id: 4, checksum: "d"}, {id: 6, checksum: "f"},
+	}}
+	index := &sliceCursor{documents: []sourceDocument{
+		{id: 2, checksum: "b"}, {id: 3, checksum: "c"}, {id: 4, checksum: "old"}, {id: 7, checksum: "g"},
+	}}
+
+	var diffs []string
+	rows, documents, err := compareDocuments(database, index, func(diff documentDiff) error {
+		diffs = append(diffs, string(diff.kind)+":"+strconv.Itoa(diff.id))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("compareDocuments() error = %v", err)
+	}
+
+	want := []string{"missing:1", "extra:3", "stale:4", "missing:6", "extra:7"}
+	if !reflect.DeepEqual(diffs, want) {
+		t.Errorf("compareDocuments() diffs = %v, want %v", diffs, want)
+	}
+	if rows != 4 || documents != 4 {
+		t.Errorf("compareDocuments() counted %d rows and %d documents, want 4 and 4", rows, documents)
+	}
+}
+
+func TestDocumentChecksumMatchesIndexedSource(t *testing.T) {
+	advertisement := model.Advertisement{Index: 42, Name: "Phone", Brand: "Acme", Price: 585, Stock: 3, Availability: "in_stock"}
+
+	fromDatabase, err := documentChecksum(advertisementDocument(&advertisement))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// _source возвращается Elasticsearch в том виде, в котором документ был проиндексирован
+	encoded, _ := json.Marshal(advertisementDocument(&advertisement))
+	var source map[string]interface{}
+	if err := json.Unmarshal(encoded, &source); err != nil {
+		t.Fatal(err)
+	}
+	fromIndex, err := documentChecksum(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fromDatabase != fromIndex {
+		t.Errorf("checksums differ: database %s, index %s", fromDatabase, fromIndex)
+	}
+
+	advertisement.Stock = 4
+	changed, _ := documentChecksum(advertisementDocument(&advertisement))
+	if changed == fromDatabase {
+		t.Errorf("checksum did not change after stock update")
+	}
+}